@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/checks"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Register existing worktrees and tmux sessions with twt.",
+	Long: `Scan the worktrees of the current repo and register any twt doesn't know about yet,
+e.g. ones created with 'git worktree add' or before 'twt list' existed, so they show up in
+'twt list'.
+
+Running tmux sessions are matched to worktrees by the name twt would give them, or by a
+pane whose working directory is inside the worktree.
+
+Use --all to scan every repo twt already has sessions for, or --session to register the
+current tmux session for the current worktree.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		all, err := flags.GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+		currentSession, err := flags.GetBool("session")
+		if err != nil {
+			fail("Couldn't check session flag")
+			return
+		}
+
+		if currentSession {
+			if shouldCancel := checks.AssertReady(); shouldCancel {
+				fail("Error when trying to run command, aborting.")
+				return
+			}
+			if err := checks.AssertTmux(); err != nil {
+				fail("%s", err)
+				return
+			}
+			result, err := workflow.AdoptCurrentSession()
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			printAdoptResult(result)
+			return
+		}
+
+		var repos []string
+		if all {
+			repos, err = workflow.KnownRepos()
+		} else {
+			var repo string
+			repo, err = git.GetBaseDir()
+			repos = []string{repo}
+		}
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		for _, repo := range repos {
+			color.Cyan("Scanning %s", repo)
+			results, err := workflow.AdoptRepo(repo)
+			if err != nil {
+				fail(" - %s", err)
+				continue
+			}
+			for _, result := range results {
+				printAdoptResult(result)
+			}
+		}
+	},
+}
+
+func printAdoptResult(result workflow.AdoptResult) {
+	worktree := result.Worktree.Path
+	if result.Worktree.Branch != "" {
+		worktree = fmt.Sprintf("%s (%s)", result.Worktree.Branch, result.Worktree.Path)
+	}
+
+	switch {
+	case result.Err != nil:
+		fail(" - %s: failed to register: %s", worktree, result.Err)
+	case result.Status == workflow.Adopted && result.MatchedBy != "":
+		color.Green(" - %s: adopted with session %s (matched by %s)", worktree, result.SessionName, result.MatchedBy)
+	case result.Status == workflow.Adopted:
+		color.Green(" - %s: adopted as %s, no session running", worktree, result.SessionName)
+	case result.Status == workflow.AlreadyRegistered && result.SessionName != "":
+		color.White(" - %s: already registered as %s", worktree, result.SessionName)
+	case result.Status == workflow.Skipped:
+		color.Yellow(" - %s: skipped, %s", worktree, result.Reason)
+	default:
+		color.White(" - %s: %s", worktree, result.Status)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().BoolP("all", "a", false, "Scan every repo twt has sessions for")
+	adoptCmd.Flags().BoolP("session", "s", false, "Register the current tmux session for the current worktree")
+}
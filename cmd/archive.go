@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/archive"
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+var archiveBase = &cobra.Command{
+	Use:   "archive",
+	Short: "Manage worktrees archived before a forced removal.",
+	Long: `Before 'twt rm --force' removes a worktree, its staged and unstaged changes, untracked
+files and branch tip are archived in the twt config dir. Disable this with
+rm.archive_on_force in the config, or per removal with --archive=false.
+
+Bring an archived worktree back with 'twt restore <branch>'. Archived commits are kept
+reachable by refs/twt/archive/* refs in the repo until their archive is restored or
+removed.`,
+}
+
+var archiveList = &cobra.Command{
+	Use:   "list",
+	Short: "List archived worktrees, newest first.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+
+		var repoPath string
+		if !all {
+			repoPath, err = git.GetBaseDir()
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+		}
+
+		archives, err := archive.List(repoPath)
+		if err != nil {
+			fail("Error listing archives: %v", err)
+			return
+		}
+		if len(archives) == 0 {
+			color.Yellow("No archived worktrees found.")
+			return
+		}
+
+		for _, saved := range archives {
+			fmt.Printf("%-40s %-8s %-10s %s\n",
+				saved.SessionName,
+				shortHash(saved.Head),
+				utils.FormatAge(saved.Age())+" ago",
+				archiveContents(saved),
+			)
+		}
+	},
+}
+
+var archiveRemove = &cobra.Command{
+	Use:   "rm <branch>",
+	Short: "Delete the archives of a branch in the current repo.",
+	Long: `Delete the archives of a branch in the current repo, and the refs keeping their commits
+reachable. Their uncommitted work is lost for good.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, err := command.Validate(args[0])
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		repoPath, err := git.GetBaseDir()
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		archives, err := archive.List(repoPath)
+		if err != nil {
+			fail("Error listing archives: %v", err)
+			return
+		}
+		deleted := 0
+		for _, saved := range archives {
+			if saved.Branch != branch {
+				continue
+			}
+			if err := saved.Delete(); err != nil {
+				fail("Couldn't delete the archive in %s: %v", saved.Dir, err)
+				return
+			}
+			deleted++
+		}
+		if deleted == 0 {
+			color.Yellow("No archives of %s found.", branch)
+			return
+		}
+		color.Green("Deleted %d archive(s) of %s.", deleted, branch)
+	},
+}
+
+var archivePrune = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete archive refs of the current repo that no archive uses.",
+	Long: `Delete the refs/twt/archive/* refs of the current repo whose archive is gone, e.g. as its
+files were deleted by hand, so git can garbage collect the commits they kept.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, err := git.GetBaseDir()
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		orphaned, err := archive.OrphanedRefs(repoPath)
+		if err != nil {
+			fail("Error listing archive refs: %v", err)
+			return
+		}
+		if len(orphaned) == 0 {
+			color.Yellow("No archive refs to delete.")
+			return
+		}
+		for _, ref := range orphaned {
+			if err := archive.DeleteRef(repoPath, ref); err != nil {
+				fail("%s", err)
+				return
+			}
+			fmt.Printf("Deleted %s\n", ref)
+		}
+	},
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func archiveContents(saved archive.Archive) string {
+	var contents []string
+	if saved.Staged {
+		contents = append(contents, "staged")
+	}
+	if saved.Unstaged {
+		contents = append(contents, "unstaged")
+	}
+	if saved.Untracked > 0 {
+		contents = append(contents, fmt.Sprintf("%d untracked", saved.Untracked))
+	}
+	if len(contents) == 0 {
+		return "clean"
+	}
+	return strings.Join(contents, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(archiveBase)
+	archiveBase.AddCommand(archiveList)
+	archiveBase.AddCommand(archiveRemove)
+	archiveBase.AddCommand(archivePrune)
+
+	archiveList.Flags().BoolP("all", "a", false, "List archives from all repositories")
+}
@@ -3,9 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/j-clemons/twt/internal/checks"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/scripts"
+	"github.com/j-clemons/twt/internal/trust"
 	"github.com/j-clemons/twt/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -44,33 +48,59 @@ func checkCommonFilesDir() {
 	for i, f := range pathFuncs {
 		dir, _ := f()
 		if _, err := os.Stat(dir); !os.IsNotExist(err) {
-			color.Green(fmt.Sprintf(" - %s exists.", dir))
+			color.Green(" - %s exists.", dir)
 		} else {
-			color.Yellow(fmt.Sprintf(" - %s doesn't exist.", checking[i]))
+			color.Yellow(" - %s doesn't exist.", checking[i])
 		}
 	}
 
-	color.Cyan("\nChecking that scripts dir contains a dir for valid commands, and that they have supported scripts.")
-	cmdsToCheck := []string{"go"}
-	scriptsToCheck := []string{"post.sh"}
+	color.Cyan("\nChecking the scripts in the scripts dir, in the order they run.")
+	scriptsDir, err := utils.GetScriptsDirPath()
+	if err != nil {
+		color.Yellow(" - No scripts to check.")
+		return
+	}
+	found, err := scripts.DiscoverAll(scriptsDir)
+	if err != nil {
+		fail(" - Couldn't read the scripts: %s", err)
+		return
+	}
+	if len(found) == 0 {
+		color.Yellow(" - No scripts found. Add them as scripts/<command>/<phase>.sh or scripts/<command>/<phase>.d/<name>.")
+		return
+	}
 
-	for i, c := range cmdsToCheck {
-		dir, _ := utils.GetScriptsDirPathForCommand(c)
-		if _, err := os.Stat(dir); !os.IsNotExist(err) {
-			color.Green(fmt.Sprintf(" - %s exists.", dir))
-		} else {
-			color.Yellow(fmt.Sprintf(" - Dir for command %s doesn't exist.", cmdsToCheck[i]))
-		}
+	cfg, err := config.Load()
+	if err != nil {
+		color.Yellow("Warning: %v, using defaults", err)
+	}
+	for _, script := range found {
+		checkScript(script, cfg.Scripts.Enabled)
+	}
+}
 
-		for _, fileName := range scriptsToCheck {
-			filePath := fmt.Sprintf("%s/%s", dir, fileName)
-			if _, err := os.Stat(filePath); !os.IsNotExist(err) {
-				color.Green(fmt.Sprintf(" - Script %s exists for command %s.", fileName, c))
-			} else {
-				color.Yellow(fmt.Sprintf(" - Script %s missing for command %s.", fileName, c))
-			}
-		}
-		fmt.Println()
+func checkScript(script scripts.Script, enabled map[string]bool) {
+	runWith := "sh"
+	if script.Binary {
+		runWith = "binary"
+	} else if len(script.Interpreter) > 0 {
+		runWith = strings.Join(script.Interpreter, " ")
+	}
+	line := fmt.Sprintf(" - %s (%s phase of %s, %s)", script.Name, script.Phase, script.Command, runWith)
+
+	switch {
+	case !script.Enabled(enabled):
+		color.Yellow("%s: disabled in the config", line)
+	case script.Problem() != "":
+		color.Red("%s: %s \u2717", line, script.Problem())
+	case !trust.IsTrusted(script.Path):
+		color.Yellow("%s: not trusted, see 'twt trust'", line)
+	case !script.Binary && len(script.Interpreter) == 0:
+		color.Yellow("%s: no #! line, run by sh", line)
+	case !script.Executable:
+		color.Yellow("%s: not executable, run by its #! interpreter", line)
+	default:
+		color.Green("%s \u2713", line)
 	}
 }
 
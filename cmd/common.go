@@ -1,59 +1,60 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/templates"
 	"github.com/j-clemons/twt/internal/tmux"
-	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
-const NEW_DIR_PERM = 0700
-
 var commonBase = &cobra.Command{
 	Use:   "common",
 	Short: "Configure twt utils.",
-	Long:  "Create a new session or switch to the session starting in the common files dir.",
+	Long: `Create a new session or switch to the session starting in the common files dir of the
+current repo. Each repo has its own common session, named after the project, which shows up
+in 'twt list' with the repo's other sessions.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		sessionName := "common"
-		hasCommonSession := tmux.HasSession(sessionName)
-
 		flags := cmd.Flags()
 		removeSession, err := flags.GetBool("remove-session")
 		if err != nil {
-			color.Red("Error fetching the remove sesion flag")
+			fail("Error fetching the remove sesion flag")
+			return
+		}
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
 			return
 		}
 		currentSession, err := tmux.GetCurrentSessionName()
 		if err != nil && removeSession {
-			color.Red("Can't remove current session")
+			fail("Can't remove current session")
+			return
 		}
 
-		if hasCommonSession {
-			tmux.SwitchToSession(sessionName)
-			if removeSession {
-				tmux.KillSession(currentSession)
-			}
+		baseDir, err := git.GetBaseDir()
+		if err != nil {
+			fail(fmt.Sprint(err))
 			return
 		}
 
-		tmux.NewSession(sessionName)
-
-		commonFilesDir, err := utils.GetCommonFilesDirPath()
+		p, err := workflow.PlanCommon(baseDir, removeSession, currentSession)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			fail(fmt.Sprint(err))
 			return
 		}
-		cdToCommonCommand := fmt.Sprintf("cd %s", commonFilesDir)
-		tmux.SendKeys(sessionName, cdToCommonCommand, "Enter")
-		tmux.SendKeys(sessionName, "clear", "Enter")
-
-		tmux.SwitchToSession(sessionName)
-		if removeSession {
-			tmux.KillSession(currentSession)
+		if dryRun != "" {
+			printPlan(p, dryRun)
+			return
+		}
+		if err := p.Execute(); err != nil {
+			printExecuteError(err)
 		}
 	},
 }
@@ -61,95 +62,139 @@ var commonBase = &cobra.Command{
 var commonInit = &cobra.Command{
 	Use:   "init",
 	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
-	Short: "Create common files in the bare repo, with templates for scripts.",
+	Short: "Create common files in the bare repo from a template.",
 	Long: `
 	'common' is a dir you can use in the bare repo dir that can house shared assets for
 	your project, e.g. a common .env file and / or startup scripts.
 
-	This command just creates the directory in the bare repo dir, and the 'scripts' folder
-	which let's you define post worktree start scripts e.g. setting env vars.
+	This command creates the directory in the bare repo dir from a template: the default
+	one just has a 'scripts/go/post.sh' placeholder, which 'twt go' runs in each new
+	worktree. The built-in go, node and compose templates add a post.sh that sets up an
+	.env from 'env/.env.example', and a 'provision.json' manifest of what each worktree
+	needs. Run 'twt common templates' to see every template, including your own.
 
-	Currently supporting post command scripts only, named 'post.sh' within each command's
-	dir in 'scripts'. Run 'twt check' to see where files should live.
+	Existing files are left alone. Use --force to update the ones that differ from the
+	template, after a diff of the changes. Run 'twt check' to see where files should live.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		templateName, err := flags.GetString("template")
+		if err != nil {
+			fail("Couldn't check template flag")
+			return
+		}
+		force, err := flags.GetBool("force")
+		if err != nil {
+			fail("Couldn't check force flag")
+			return
+		}
+		confirm, err := flags.GetBool("confirm")
+		if err != nil {
+			fail("Couldn't check confirm flag")
+			return
+		}
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
 		baseDir, err := git.GetBaseDir()
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			fail(fmt.Sprint(err))
 			return
 		}
 
-		color.Cyan("Setting up common file dir.\n\n")
-		path := baseDir
-		toCreate := []string{"common", "scripts"}
-
-		// Create nested dirs
-		for _, dir := range toCreate {
-			path = fmt.Sprintf("%s/%s", path, dir)
-			if _, err := os.Stat(path); !os.IsNotExist(err) {
-				color.Yellow(fmt.Sprintf("Dir %s exists - skipping.", dir))
-				continue
+		template, err := templates.Get(templateName)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		p, changes, err := workflow.PlanCommonInit(baseDir, template, force)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		if dryRun != "" {
+			printPlan(p, dryRun)
+			if dryRun == "text" {
+				printChanges(changes)
 			}
-			color.Cyan(fmt.Sprintf("Creating %s.", path))
+			return
+		}
 
-			if err := os.Mkdir(path, NEW_DIR_PERM); err != nil {
-				color.Red(fmt.Sprintf("Dir %s couldn't be created: %s.", dir, err))
+		if force && len(changes) > 0 {
+			printChanges(changes)
+			if !confirm && !askConfirmation(fmt.Sprintf("Update %d file(s)? (y/N): ", len(changes))) {
+				color.Yellow("Operation cancelled")
 				return
-			} else {
-				color.Green(fmt.Sprintf("Successfully created %s in %s.", dir, path))
 			}
 		}
 
-		color.Cyan("\n\nSetting up scripts dirs for each command.\n\n")
-		// Command scripts for each command
-		cmdDirs := []string{"go"}
-		scripts := []string{"post.sh"}
-		for _, dir := range cmdDirs {
-			newPath := fmt.Sprintf("%s/%s", path, dir)
-
-			if _, err := os.Stat(newPath); !os.IsNotExist(err) {
-				color.Yellow(fmt.Sprintf("Dir %s exists - skipping.", dir))
-				continue
-			}
-			color.Cyan(fmt.Sprintf("Creating %s.", newPath))
-
-			if err := os.Mkdir(newPath, NEW_DIR_PERM); err != nil {
-				color.Red(fmt.Sprintf("Dir %s couldn't be created: %s.", dir, err))
-				return
-			} else {
-				color.Green(fmt.Sprintf("Successfully created %s in %s.", dir, newPath))
-			}
+		color.Cyan("Setting up common file dir from the %s template.\n", template.Name)
+		for _, note := range p.Notes {
+			color.Yellow("%s.", note)
+		}
+		if err := p.Execute(); err != nil {
+			printExecuteError(err)
+			return
+		}
+		for _, step := range p.Steps {
+			color.Green("Done: %s.", step.Description)
+		}
+	},
+}
 
-			for _, fileName := range scripts {
-				filePath := fmt.Sprintf("%s/%s", newPath, fileName)
-
-				if _, err := os.Stat(filePath); !os.IsNotExist(err) {
-					color.Yellow(fmt.Sprintf("File %s exists - skipping.", dir))
-					continue
-				}
-
-				if f, err := os.Create(filePath); err != nil {
-					color.Red(fmt.Sprintf("File %s couldn't be created: %s.", filePath, err))
-					f.Close()
-					return
-				} else {
-					color.Green(fmt.Sprintf("Successfully created %s", filePath))
-					os.Chmod(filePath, 0700)
-					f.WriteString("#!/bin/bash\n\n")
-					f.WriteString("echo \"Enter your scripts here\"")
-					f.Close()
-				}
-			}
+var commonTemplates = &cobra.Command{
+	Use:   "templates",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Short: "List the templates common init can use.",
+	Long: `List the built-in templates, and your own: dirs in the 'templates' dir of the twt
+config dir, and dirs or git repos named in common.templates in the config.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := templates.List()
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		for _, template := range all {
+			fmt.Printf("%-15s %s\n", template.Name, template.Source)
 		}
 	},
 }
 
+func printChanges(changes []workflow.FileChange) {
+	for _, change := range changes {
+		diff, err := change.Diff()
+		if err != nil {
+			fail("Couldn't diff %s: %s", change.Path, err)
+			continue
+		}
+		fmt.Println(diff)
+	}
+}
+
+func askConfirmation(prompt string) bool {
+	fmt.Print(prompt)
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
 func init() {
 	// Register root
 	rootCmd.AddCommand(commonBase)
 
 	// Config topics
 	commonBase.AddCommand(commonInit)
+	commonBase.AddCommand(commonTemplates)
 
 	commonBase.Flags().BoolP("remove-session", "r", false, "Remove current session (not worktree) after.")
+	addDryRunFlag(commonBase)
+	commonInit.Flags().StringP("template", "t", templates.Default, "Template to create the common files from, see 'twt common templates'")
+	commonInit.Flags().BoolP("force", "f", false, "Update files that differ from the template")
+	commonInit.Flags().BoolP("confirm", "y", false, "Skip confirmation prompt")
+	addDryRunFlag(commonInit)
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/compose"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var composeCmd = &cobra.Command{
+	Use:   "compose <branch> -- <args>...",
+	Short: "Run docker compose in a branch's worktree, as the worktree's own project.",
+	Long: `Run docker compose with the given arguments in a branch's worktree, with the
+COMPOSE_PROJECT_NAME twt derives for it, e.g. 'twt compose feature -- up -d'. Each worktree
+gets its own project, so their containers, networks and volumes don't clash. The same name
+is exported in the worktree's session and to its scripts.
+
+With --workspace, runs in each of the branch's worktrees in the workspace that has a
+compose file, as their own projects. twt exits with compose's exit code.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceName, err := cmd.Flags().GetString("workspace")
+		if err != nil {
+			fail("Couldn't check workspace flag")
+			return
+		}
+		branchArg, composeArgs, err := compose.SplitArgs(args, cmd.ArgsLenAtDash())
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		branch, err := command.Validate(branchArg)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		var session state.SessionInfo
+		if workspaceName != "" {
+			session, err = workspaceSession(workspaceName, branch)
+		} else {
+			session, err = repoSession(branch)
+		}
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		projects := workflow.ComposeProjects(session)
+		if len(projects) == 0 {
+			color.Yellow("No worktree of %s has a compose file.", session.Name)
+			return
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			color.Yellow("Warning: %v, using the default compose command", err)
+		}
+		for _, project := range projects {
+			if len(projects) > 1 {
+				color.Cyan("%s in %s:", project.Name, project.Dir)
+			}
+			err := compose.Run(cfg.Compose.Command, project.Name, project.Dir, composeArgs...)
+			if err == nil {
+				continue
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else {
+				fail("%s", err)
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(composeCmd)
+
+	composeCmd.Flags().StringP("workspace", "w", "", "Run in each of the branch's worktrees in the workspace")
+}
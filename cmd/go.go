@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -20,37 +23,73 @@ var goToWorktree = &cobra.Command{
 	or not. If this isn't desired, rename / delete the existing session.
 
 	Also switches to a new session if a worktree exists (ie. the branch is checked out).
+
+	Outside tmux, the tmux server is started if needed and the session is attached to.
+	With --no-tmux only the worktree is created, and its path printed, e.g. for
+	cd "$(twt go <branch> --no-tmux)".
 	`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1)),
 	Run: func(cmd *cobra.Command, args []string) {
-		shouldCancel := checks.AssertReady()
-		if shouldCancel {
-			color.Red("Error when trying to run command, aborting.")
+		noTmux, err := cmd.Flags().GetBool("no-tmux")
+		if err != nil {
+			fail("Couldn't check no-tmux flag")
+			return
+		}
+		workspaceName, err := cmd.Flags().GetString("workspace")
+		if err != nil {
+			fail("Couldn't check workspace flag")
+			return
+		}
+		if workspaceName != "" {
+			if noTmux {
+				fail("--no-tmux can't be used with --workspace")
+				return
+			}
+			// The workspace's repos are used rather than the current dir's
+			if err := checks.AssertTmuxInstalled(); err != nil {
+				fail("%s", err)
+				return
+			}
+		} else if noTmux {
+			// Keep stdout for the path
+			color.Output = os.Stderr
+			if err := checks.AssertGit(); err != nil {
+				fail("%s", err)
+				return
+			}
+		} else if shouldCancel := checks.AssertReady(); shouldCancel {
+			fail("Error when trying to run command, aborting.")
 			return
 		}
 
 		branch := args[0]
-		branch, err := command.Validate(branch)
+		branch, err = command.Validate(branch)
 		if err != nil {
-			color.Red(err.Error())
+			fail("%s", err)
 			return
 		}
 
 		flags := cmd.Flags()
 		removeSession, err := flags.GetBool("remove-session")
 		if err != nil {
-			color.Red("Error fetching the remove session flag")
+			fail("Error fetching the remove session flag")
 			return
 		}
 		noScripts, err := flags.GetBool("no-scripts")
 		if err != nil {
-			color.Red("Couldn't fetch the run scripts flag")
+			fail("Couldn't fetch the run scripts flag")
+			return
+		}
+
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
 			return
 		}
 
 		currentSession, err := tmux.GetCurrentSessionName()
 		if err != nil && removeSession {
-			color.Red("Can't remove current session.")
+			fail("Can't remove current session.")
 			return
 		}
 
@@ -59,18 +98,66 @@ var goToWorktree = &cobra.Command{
 			RemoveCurrentSession: removeSession,
 			NoScripts:            noScripts,
 			CurrentSession:       currentSession,
+			NoTmux:               noTmux,
+		}
+
+		if workspaceName != "" {
+			goToWorkspace(workspaceName, opts, dryRun)
+			return
+		}
+
+		if dryRun != "" {
+			p, err := workflow.PlanGo(opts)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			printPlan(p, dryRun)
+			return
 		}
 
 		err = workflow.ExecuteGo(opts)
 		if err != nil {
-			color.Red(err.Error())
+			printExecuteError(err)
+			return
+		}
+		if noTmux {
+			worktreePath, err := workflow.GoWorktreePath(branch)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			fmt.Println(worktreePath)
 		}
 	},
 }
 
+func goToWorkspace(workspaceName string, opts workflow.GoOptions, dryRun string) {
+	workspace, err := workflow.LoadWorkspace(workspaceName)
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+	p, err := workflow.PlanGoWorkspace(workspace, opts)
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+	if dryRun != "" {
+		printPlan(p, dryRun)
+		return
+	}
+	if err := p.Execute(); err != nil {
+		printExecuteError(err)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(goToWorktree)
 
-	goToWorktree.Flags().BoolP("no-scripts", "N", false, "Don't run any scripts in the common files dir if they exist for this command.")
+	goToWorktree.Flags().BoolP("no-scripts", "N", false, "Don't run any scripts, or set up resources, from the common files dir.")
 	goToWorktree.Flags().BoolP("remove-session", "r", false, "Remove current session (not worktree) after.")
+	goToWorktree.Flags().StringP("workspace", "w", "", "Go to the branch in every repo of the workspace, in one session")
+	goToWorktree.Flags().Bool("no-tmux", false, "Only create the worktree and print its path, without a tmux session or scripts.")
+	addDryRunFlag(goToWorktree)
 }
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var hibernateCmd = &cobra.Command{
+	Use:   "hibernate [branch]",
+	Short: "Stop a session to free resources, keeping its worktree and layout.",
+	Long: `Save the layout of a twt session, interrupt the processes running in its panes (e.g.
+dev servers) and kill the session. The worktree and state entry are kept, and 'twt go' or
+'twt restore' brings the session back with its layout.
+
+Hibernate the session for a branch of the current repo, or with --idle-for every detached
+twt session not used for that long, e.g. --idle-for 3d.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		idleFor, err := flags.GetString("idle-for")
+		if err != nil {
+			fail("Couldn't check idle-for flag")
+			return
+		}
+		grace, err := flags.GetDuration("grace")
+		if err != nil {
+			fail("Couldn't check grace flag")
+			return
+		}
+
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			color.Yellow("Warning: %v, using defaults", err)
+		}
+		currentSession, _ := tmux.GetCurrentSessionName()
+		opts := workflow.HibernateOptions{
+			RestoreCommands: cfg.Sessions.RestoreCommands,
+			Grace:           grace,
+			Destinations:    cfg.Remove.Destinations,
+			CurrentSession:  currentSession,
+		}
+
+		var sessions []state.SessionInfo
+		switch {
+		case idleFor != "" && len(args) == 1:
+			fail("Give either a branch or --idle-for, not both.")
+			return
+
+		case idleFor != "":
+			duration, err := utils.ParseDuration(idleFor)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			sessions, err = workflow.IdleSessions(duration, currentSession)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+
+		case len(args) == 1:
+			branch, err := command.Validate(args[0])
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			sessionName := utils.GenerateSessionNameFromBranch(branch)
+			session, exists, err := state.GetSession(sessionName)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if !exists {
+				fail("No twt session registered for %s.", branch)
+				return
+			}
+			if !session.IsActive() {
+				color.Yellow("%s isn't running.", sessionName)
+				return
+			}
+			sessions = []state.SessionInfo{session}
+
+		default:
+			fail("Give a branch to hibernate, or --idle-for.")
+			return
+		}
+
+		if len(sessions) == 0 {
+			color.Yellow("No sessions to hibernate.")
+			return
+		}
+
+		if dryRun != "" {
+			printPlan(workflow.PlanHibernate(sessions, opts), dryRun)
+			return
+		}
+
+		color.Cyan("Hibernating %d session(s)...", len(sessions))
+		printSessionResults(workflow.HibernateSessions(sessions, opts), "hibernated")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hibernateCmd)
+
+	hibernateCmd.Flags().StringP("idle-for", "i", "", "Hibernate every detached session unused for this long, e.g. 3d or 12h")
+	addDryRunFlag(hibernateCmd)
+	hibernateCmd.Flags().Duration("grace", 10*time.Second, "How long pane processes get to exit before the session is killed")
+}
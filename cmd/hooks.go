@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/checks"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+// twtHooks returns the tmux hooks twt can install: access tracking whenever the client
+// switches or attaches to a session, and optionally saving session layouts.
+func twtHooks(autosave bool) ([]tmux.Hook, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find the twt executable: %w", err)
+	}
+
+	touch := fmt.Sprintf("run-shell -b \"%s touch '#{session_name}'\"", exe)
+	hooks := []tmux.Hook{
+		{Event: "client-session-changed", Index: tmux.TouchHookIndex, Command: touch},
+		{Event: "client-attached", Index: tmux.TouchHookIndex, Command: touch},
+	}
+
+	if autosave {
+		save := fmt.Sprintf("run-shell -b \"%s save --all --quiet\"", exe)
+		hooks = append(hooks,
+			tmux.Hook{Event: "client-session-changed", Index: tmux.AutosaveHookIndex, Command: save},
+			tmux.Hook{Event: "client-detached", Index: tmux.AutosaveHookIndex, Command: save},
+		)
+	}
+	return hooks, nil
+}
+
+var hooksBase = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage the tmux hooks twt uses to track session access.",
+	Long: `twt can track when each session was last used through tmux hooks that run 'twt touch'
+whenever a client switches or attaches to a session. The history powers 'twt last',
+'twt recent', MRU sorting in 'twt list' and picking where to go after 'twt rm'.
+
+With --autosave, session layouts are also saved on every switch and detach, so 'twt restore'
+can recreate them after the tmux server stops.
+
+Hooks set with 'install' last until the tmux server stops. Use 'install --print' for lines to
+add to your tmux.conf instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks, err := twtHooks(true)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		for _, hook := range hooks {
+			if installed := tmux.GetHook(hook); installed != "" {
+				color.Green(" - %s[%d]: %s", hook.Event, hook.Index, installed)
+			} else {
+				color.Yellow(" - %s[%d]: not installed", hook.Event, hook.Index)
+			}
+		}
+	},
+}
+
+var hooksInstall = &cobra.Command{
+	Use:   "install",
+	Short: "Install the tmux hooks in the running tmux server.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		print, err := flags.GetBool("print")
+		if err != nil {
+			fail("Couldn't check print flag")
+			return
+		}
+		autosave, err := flags.GetBool("autosave")
+		if err != nil {
+			fail("Couldn't check autosave flag")
+			return
+		}
+
+		hooks, err := twtHooks(autosave)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		if print {
+			for _, hook := range hooks {
+				fmt.Println(tmux.HookConfLine(hook))
+			}
+			return
+		}
+
+		if err := checks.AssertTmux(); err != nil {
+			fail("%s", err)
+			return
+		}
+		for _, hook := range hooks {
+			if err := tmux.SetHook(hook); err != nil {
+				fail("%s", err)
+				return
+			}
+			color.Green("Installed %s hook.", hook.Event)
+		}
+	},
+}
+
+var hooksUninstall = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the tmux hooks from the running tmux server.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checks.AssertTmux(); err != nil {
+			fail("%s", err)
+			return
+		}
+
+		hooks, err := twtHooks(true)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		for _, hook := range hooks {
+			if tmux.GetHook(hook) == "" {
+				continue
+			}
+			if err := tmux.UnsetHook(hook); err != nil {
+				fail("%s", err)
+				return
+			}
+			color.Green("Removed %s hook.", hook.Event)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hooksBase)
+
+	hooksBase.AddCommand(hooksInstall)
+	hooksBase.AddCommand(hooksUninstall)
+
+	hooksInstall.Flags().BoolP("print", "p", false, "Print tmux.conf lines instead of installing")
+	hooksInstall.Flags().BoolP("autosave", "s", false, "Also save session layouts on every switch and detach")
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/j-clemons/twt/internal/git"
@@ -29,11 +28,11 @@ Use --all to show sessions from all repositories.`,
 				if ok {
 					listAndDisplayAllSessions()
 				} else {
-					color.Red("Error listing sessions: %v", err)
+					fail("Error listing sessions: %v", err)
 					return
 				}
 			} else {
-				tui.RunListTui(sessions)
+				tui.RunListTui(state.ListSessionsForCurrentRepo, sessions)
 			}
 		}
 	},
@@ -42,10 +41,10 @@ Use --all to show sessions from all repositories.`,
 func listAndDisplayAllSessions() {
 	sessions, err := state.ListAllSessions()
 	if err != nil {
-		color.Red("Error listing sessions: %v", err)
+		fail("Error listing sessions: %v", err)
 		return
 	}
-	tui.RunListTui(sessions)
+	tui.RunListTui(state.ListAllSessions, sessions)
 }
 
 func init() {
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/plan"
+)
+
+const (
+	dryRunText = "text"
+	dryRunJSON = "json"
+)
+
+// addDryRunFlag adds --dry-run, which prints the plan a command would carry out instead of
+// running it. --dry-run=json prints it as JSON for scripts.
+func addDryRunFlag(c *cobra.Command) {
+	c.Flags().String("dry-run", "", "Print the planned operations without running them (text or json)")
+	c.Flags().Lookup("dry-run").NoOptDefVal = dryRunText
+}
+
+// dryRunFormat returns the --dry-run format, or "" to run the command for real.
+func dryRunFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString("dry-run")
+	if err != nil {
+		return "", fmt.Errorf("Couldn't check dry-run flag")
+	}
+	switch format {
+	case "", dryRunText, dryRunJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("Unknown dry-run format %q, use text or json", format)
+}
+
+func printPlan(p *plan.Plan, format string) {
+	if err := p.Print(os.Stdout, format == dryRunJSON); err != nil {
+		color.Red("%s", err)
+	}
+}
+
+// printExecuteError reports a failed plan, and what was rolled back if it was undone, and
+// sets the exit code: exitWarnings if the plan completed but warned, otherwise exitFailed.
+func printExecuteError(err error) {
+	var warnings *plan.Warnings
+	if errors.As(err, &warnings) {
+		color.Yellow("Done, but:")
+		for _, message := range warnings.Messages {
+			color.Yellow(" - %s", message)
+		}
+		exitCode = exitWarnings
+		return
+	}
+
+	color.Red("%s", err)
+	exitCode = exitFailed
+
+	var failure *plan.Failure
+	if !errors.As(err, &failure) {
+		return
+	}
+	if len(failure.RolledBack) > 0 {
+		color.Yellow("Rolled back:")
+		for _, rollback := range failure.RolledBack {
+			color.Yellow(" - %s", rollback)
+		}
+	}
+	if len(failure.RollbackErrs) > 0 {
+		color.Red("Couldn't roll back, clean up by hand:")
+		for _, err := range failure.RollbackErrs {
+			color.Red(" - %s", err)
+		}
+	}
+}
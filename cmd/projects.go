@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/checks"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/tui"
+	"github.com/j-clemons/twt/internal/tui/picker"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Pick any branch of any project to go to.",
+	Long: `Search the discovery roots in the config for bare repos, and pick a branch of any of
+them, or of a registered repo, to go to as with 'twt go', even if twt has never been used
+in that repo.
+
+The roots are searched up to discovery.max_depth dirs down, skipping dirs matching
+discovery.ignore. What's found is cached for discovery.refresh_after; use --refresh to
+search again. --list prints the projects instead of picking one.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		refresh, err := flags.GetBool("refresh")
+		if err != nil {
+			fail("Couldn't check refresh flag")
+			return
+		}
+		list, err := flags.GetBool("list")
+		if err != nil {
+			fail("Couldn't check list flag")
+			return
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			color.Yellow("Warning: %v, using defaults", err)
+		}
+		projects, err := workflow.AllProjects(cfg.Discovery, refresh)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		if list {
+			for _, project := range projects {
+				fmt.Printf("%-20s %s\n", project.Name, project.Path)
+				if len(project.Branches) > 0 {
+					color.White("  %s", strings.Join(project.Branches, " "))
+				}
+			}
+			return
+		}
+
+		entry, ok := tui.RunProjectPicker(picker.Entries(projects), cfg)
+		if !ok {
+			return
+		}
+		goToProject(entry)
+	},
+}
+
+// goToProject runs go for the picked branch from the project's base dir.
+func goToProject(entry picker.Entry) {
+	if err := os.Chdir(entry.Project.Path); err != nil {
+		fail("Couldn't change to %s: %s", entry.Project.Path, err)
+		return
+	}
+	if shouldCancel := checks.AssertReady(); shouldCancel {
+		fail("Error when trying to run command, aborting.")
+		return
+	}
+
+	currentSession, _ := tmux.GetCurrentSessionName()
+	err := workflow.ExecuteGo(workflow.GoOptions{
+		Branch:         entry.Branch,
+		CurrentSession: currentSession,
+	})
+	if err != nil {
+		printExecuteError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+
+	projectsCmd.Flags().Bool("refresh", false, "Search the discovery roots again instead of using the cached index")
+	projectsCmd.Flags().BoolP("list", "l", false, "Print the projects and their branches instead of picking one")
+}
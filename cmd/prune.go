@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Clean up after sessions whose worktrees were deleted without twt.",
+	Long: `Clean up after twt sessions whose worktrees no longer exist, e.g. deleted with rm -rf
+rather than 'twt rm': their resources are torn down, they're killed if still running and
+unregistered, and git forgets the deleted worktrees.
+
+Prunes the sessions of the current repo, or with --all of every repo.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		var sessions []state.SessionInfo
+		if all {
+			sessions, err = state.ListAllSessions()
+		} else {
+			sessions, err = state.ListSessionsForCurrentRepo()
+		}
+		if err != nil {
+			fail("Error listing sessions: %v", err)
+			return
+		}
+
+		prunable := workflow.PrunableSessions(sessions)
+		if len(prunable) == 0 {
+			color.Yellow("No sessions to prune.")
+			return
+		}
+
+		p, err := workflow.PlanPrune(prunable)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		if dryRun != "" {
+			printPlan(p, dryRun)
+			return
+		}
+
+		color.Cyan("Pruning %d session(s)...", len(prunable))
+		printSessionResults(workflow.ExecuteBulk(p, prunable), "pruned")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().BoolP("all", "a", false, "Prune sessions from all repositories")
+	addDryRunFlag(pruneCmd)
+}
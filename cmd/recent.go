@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/checks"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Switch to the previously used twt session.",
+	Long: `Toggle to the most recently used twt session other than the current one, across
+all repos. Only running sessions are considered.
+
+Access is tracked when switching with twt, or on every switch with 'twt hooks install'.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checks.AssertTmux(); err != nil {
+			fail("%s", err)
+			return
+		}
+
+		currentSession, err := tmux.GetCurrentSessionName()
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		previous, ok := state.MostRecentActive("", currentSession)
+		if !ok {
+			color.Yellow("No other running twt session to switch to.")
+			return
+		}
+
+		// Touch the one being left too, so running last again toggles back
+		state.UpdateLastAccessed(currentSession)
+		tmux.SwitchToSession(previous.Name)
+		state.UpdateLastAccessed(previous.Name)
+	},
+}
+
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List twt sessions, most recently used first.",
+	Long: `List twt sessions for the current repo ordered by last access. Use --all to list
+sessions from every repo.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+
+		var repoPath string
+		if !all {
+			repoPath, err = git.GetBaseDir()
+			if err != nil {
+				if _, ok := err.(*git.NotInGitDirError); !ok {
+					fail("%s", err)
+					return
+				}
+			}
+		}
+
+		sessions, err := state.ListRecentSessions(repoPath)
+		if err != nil {
+			fail("Error listing sessions: %v", err)
+			return
+		}
+		if len(sessions) == 0 {
+			color.Yellow("No TWT sessions found.")
+			return
+		}
+
+		for _, session := range sessions {
+			line := fmt.Sprintf("%-40s %-10s %s ago", session.Name, session.Status, utils.FormatAge(session.TimeSinceAccessed()))
+			if session.IsActive() {
+				color.Green("%s", line)
+			} else {
+				color.White("%s", line)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+	rootCmd.AddCommand(recentCmd)
+
+	recentCmd.Flags().BoolP("all", "a", false, "List sessions from all repositories")
+}
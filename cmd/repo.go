@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+var repoBase = &cobra.Command{
+	Use:   "repo",
+	Short: "Manage the repos twt knows about.",
+	Long: `Repos are registered when 'twt go' or 'twt adopt' is run in them, or by hand with
+'twt repo add'. Each gets a short alias, by default its dir name without .git.
+
+Any command can then be run against a registered repo from anywhere with
+-R/--repo <alias|path>, e.g. 'twt -R api go feature'.`,
+}
+
+var repoAdd = &cobra.Command{
+	Use:   "add [path]",
+	Short: "Register the repo at path, or the current one.",
+	Long: `Register the repo at path, or the current repo if no path is given. Registering an
+already registered repo with --alias renames its alias.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		alias, err := cmd.Flags().GetString("alias")
+		if err != nil {
+			fail("Couldn't check alias flag")
+			return
+		}
+
+		path := "."
+		if len(args) == 1 {
+			path = args[0]
+		}
+		baseDir, err := git.BaseDirOf(path)
+		if err != nil {
+			fail("%s: %s", path, err)
+			return
+		}
+
+		repo, err := state.AddRepo(baseDir, alias)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		color.Green("Registered %s as %s", repo.Path, repo.Alias)
+	},
+}
+
+var repoRemove = &cobra.Command{
+	Use:   "rm <alias|path>",
+	Short: "Forget a registered repo.",
+	Long:  "Forget a registered repo. Its worktrees and sessions are left alone.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := state.RemoveRepo(args[0])
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		color.Green("Removed %s (%s)", repo.Alias, repo.Path)
+	},
+}
+
+var repoList = &cobra.Command{
+	Use:   "list",
+	Short: "List registered repos and their aliases.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		repos, err := state.ListRepos()
+		if err != nil {
+			fail("Error listing repos: %v", err)
+			return
+		}
+		if len(repos) == 0 {
+			color.Yellow("No repos registered.")
+			return
+		}
+
+		for _, repo := range repos {
+			line := fmt.Sprintf("%-20s %s", repo.Alias, repo.Path)
+			if _, err := os.Stat(repo.Path); err != nil {
+				color.Yellow(line + " (missing)")
+				continue
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repoBase)
+
+	repoBase.AddCommand(repoAdd)
+	repoBase.AddCommand(repoRemove)
+	repoBase.AddCommand(repoList)
+
+	repoAdd.Flags().StringP("alias", "a", "", "Alias to use instead of the repo's dir name")
+}
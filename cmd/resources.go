@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var resourcesCmd = &cobra.Command{
+	Use:   "resources <branch>",
+	Short: "List the resources set up for a branch's worktree.",
+	Long: `List the resources from the repo's provision.json set up for a branch's worktree, in
+the order they were set up. 'twt rm' and 'twt prune' tear them down in reverse order.
+
+With --workspace, lists those of the branch's workspace session, across its repos.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceName, err := cmd.Flags().GetString("workspace")
+		if err != nil {
+			fail("Couldn't check workspace flag")
+			return
+		}
+		branch, err := command.Validate(args[0])
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		sessionName := utils.GenerateSessionNameFromBranch(branch)
+		if workspaceName != "" {
+			sessionName = workflow.WorkspaceSessionName(workspaceName, branch)
+		}
+		session, exists, err := state.GetSession(sessionName)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		if !exists {
+			fail("No twt session registered for %s.", branch)
+			return
+		}
+		if len(session.Resources) == 0 {
+			color.Yellow("No resources set up for %s.", sessionName)
+			return
+		}
+
+		for _, resource := range session.Resources {
+			color.Green("%s  set up %s ago", resource.Name, utils.FormatAge(time.Since(resource.SetupAt)))
+			if session.Workspace != "" {
+				color.White("  repo:     %s", resource.RepoPath)
+			}
+			color.White("  dir:      %s", resource.Dir)
+			if resource.Teardown == "" {
+				color.Yellow("  teardown: none, it's forgotten on removal")
+			} else {
+				color.White("  teardown: %s", resource.Teardown)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resourcesCmd)
+
+	resourcesCmd.Flags().StringP("workspace", "w", "", "List the resources of the branch's session in the workspace")
+}
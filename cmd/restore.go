@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/archive"
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [branch]",
+	Short: "Recreate inactive twt sessions, with their saved layout.",
+	Long: `Recreate twt sessions that are registered but not running, e.g. after a reboot, in their
+worktree and with the layout recorded by 'twt save' if there is one.
+
+Restores the session for the given branch of the current repo, or with --all every inactive
+twt session. Sessions are created in the background, use 'twt go' or 'twt list' to switch.
+
+If the branch's worktree was removed with 'twt rm --force' and archived, the worktree is
+recreated from the newest archive with its uncommitted changes and untracked files, see
+'twt archive list'.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		if all {
+			sessions, err := state.ListAllSessions()
+			if err != nil {
+				fail("Error listing sessions: %v", err)
+				return
+			}
+			p, inactive, err := workflow.PlanRestoreSessions(sessions)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if dryRun != "" {
+				printPlan(p, dryRun)
+				return
+			}
+			printSessionResults(workflow.ExecuteBulk(p, inactive), "restored")
+			return
+		}
+		if len(args) == 0 {
+			fail("Give a branch to restore, or --all.")
+			return
+		}
+		branch, err := command.Validate(args[0])
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		sessionName := utils.GenerateSessionNameFromBranch(branch)
+		session, exists, err := state.GetSession(sessionName)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		// A forced rm unregisters the session, so an archive is only looked for without one,
+		// or if its worktree is gone anyway
+		if _, statErr := os.Stat(session.WorktreePath); !exists || os.IsNotExist(statErr) {
+			restored, err := restoreFromArchive(branch, dryRun)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if restored {
+				return
+			}
+		}
+		if !exists {
+			fail("No twt session registered for %s.", branch)
+			return
+		}
+		if session.IsActive() {
+			color.Yellow("%s is already running.", sessionName)
+			return
+		}
+
+		p, err := workflow.PlanRestoreSession(session)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		if dryRun != "" {
+			printPlan(p, dryRun)
+			return
+		}
+		if err := p.Execute(); err != nil {
+			printExecuteError(err)
+			return
+		}
+		color.Green("Restored %s.", sessionName)
+	},
+}
+
+// restoreFromArchive brings back the newest archive of branch in the current repo, or prints
+// the plan for it with dryRun. It reports false if there's no archive to restore.
+func restoreFromArchive(branch, dryRun string) (bool, error) {
+	baseDir, err := git.GetBaseDir()
+	if err != nil {
+		return false, err
+	}
+	saved, ok, err := archive.Latest(baseDir, branch)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	p, err := workflow.PlanRestoreArchive(saved)
+	if err != nil {
+		return false, err
+	}
+	if dryRun != "" {
+		printPlan(p, dryRun)
+		return true, nil
+	}
+	if err := p.Execute(); err != nil {
+		printExecuteError(err)
+		return true, nil
+	}
+	color.Green("Restored %s from the archive of %s.", saved.SessionName, saved.CreatedAt.Format("2006-01-02 15:04"))
+	return true, nil
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolP("all", "a", false, "Restore every inactive twt session")
+	addDryRunFlag(restoreCmd)
+}
@@ -4,64 +4,87 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/j-clemons/twt/internal/checks"
 	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/config"
 	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
 	"github.com/j-clemons/twt/internal/state"
 	"github.com/j-clemons/twt/internal/tmux"
 	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
 var removeWorktree = &cobra.Command{
 	Use:   "rm <branch>",
 	Short: "Remove a git worktree, tmux session, and optionally the linked branch.",
-	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	Long: `Remove the worktree and session for a branch, and optionally the branch.
+
+With --workspace, the branch's worktree (and branch) is removed from every repo of the
+workspace, along with its session.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1)),
 	Run: func(cmd *cobra.Command, args []string) {
-		shouldCancel := checks.AssertReady()
-		if shouldCancel {
-			color.Red("Error when trying to run command, aborting.")
+		flags := cmd.Flags()
+		workspaceName, err := flags.GetString("workspace")
+		if err != nil {
+			fail("Couldn't check workspace flag")
 			return
 		}
+		// A workspace's repos are used rather than the current dir's
+		if workspaceName == "" {
+			if shouldCancel := checks.AssertReady(); shouldCancel {
+				fail("Error when trying to run command, aborting.")
+				return
+			}
+		}
 
 		branch := args[0]
-		branch, err := command.Validate(branch)
+		branch, err = command.Validate(branch)
 		if err != nil {
-			color.Red(err.Error())
+			fail("%s", err)
 			return
 		}
 
-		sessionName := utils.GenerateSessionNameFromBranch(branch)
-		worktreeName := utils.GenerateWorktreeNameFromBranch(branch)
-
-		flags := cmd.Flags()
 		deleteBranch, err := flags.GetBool("delete-branch")
 		if err != nil {
-			color.Red("Couldn't check delete-branch flag")
+			fail("Couldn't check delete-branch flag")
 			return
 		}
 		force, err := flags.GetBool("force")
 		if err != nil {
-			color.Red("Couldn't check force flag")
+			fail("Couldn't check force flag")
+			return
+		}
+
+		archiveWork, err := flags.GetBool("archive")
+		if err != nil {
+			fail("Couldn't check archive flag")
 			return
 		}
 
 		confirm, err := flags.GetBool("confirm")
 		if err != nil {
-			color.Red("Couldn't check confirm flag")
+			fail("Couldn't check confirm flag")
+			return
+		}
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
 			return
 		}
 
 		// Ask for confirmation unless --force is used
-		if !force && !confirm {
+		if !force && !confirm && dryRun == "" {
 			fmt.Printf("Are you sure you want to remove worktree and session for branch '%s'? (y/N): ", branch)
 			reader := bufio.NewReader(os.Stdin)
 			response, err := reader.ReadString('\n')
 			if err != nil {
-				color.Red("Error reading confirmation")
+				fail("Error reading confirmation")
 				return
 			}
 			response = strings.TrimSpace(strings.ToLower(response))
@@ -72,86 +95,121 @@ var removeWorktree = &cobra.Command{
 		}
 		nextBranch, err := flags.GetString("target")
 		if err != nil {
-			color.Red("Couldn't fetch next branch without error")
+			fail("Couldn't fetch next branch without error")
 			return
 		}
 		var targetSession string
 		if nextBranch != "" {
-			targetSession = utils.GenerateSessionNameFromBranch(nextBranch)
+			if workspaceName != "" {
+				targetSession = workflow.WorkspaceSessionName(workspaceName, nextBranch)
+			} else {
+				targetSession = utils.GenerateSessionNameFromBranch(nextBranch)
+			}
 			if !tmux.HasSession(targetSession) {
-				color.Red(fmt.Sprintf("Target session '%s' doesn't exist", targetSession))
+				fail("Target session '%s' doesn't exist", targetSession)
 				return
 			}
 		}
 
-		// Git cleanup
-		branchExistsAndCheckedOut := git.HasBranch(branch, true)
-		worktreeExists := git.HasWorktree(branch)
-		if !branchExistsAndCheckedOut {
-			color.Red(fmt.Sprintf("Branch %s doesn't exist, or isn't checked out", branch))
-			return
-		}
-		if !worktreeExists {
-			color.Red(fmt.Sprintf("Can't delete worktree %s as it doesn't exist", branch))
-			return
+		cfg, err := config.Load()
+		if err != nil {
+			color.Yellow("Warning: %v, using default rm settings", err)
 		}
-		if errs := git.RemoveWorktree(worktreeName, branch, force, deleteBranch); len(errs) > 0 {
-			for _, err := range errs {
-				color.Red(fmt.Sprintf("Error removing worktree: %s", err))
-			}
-			return
+		if !flags.Changed("archive") {
+			archiveWork = force && cfg.Remove.ArchiveOnForce
 		}
 
-		// Tmux cleanup
-		existingSessions, err := tmux.ListSessions(true)
-		if err != nil {
-			color.Red(fmt.Sprint(err))
-			return
+		var session state.SessionInfo
+		if workspaceName != "" {
+			session, err = workspaceSession(workspaceName, branch)
+		} else {
+			session, err = repoSession(branch)
 		}
-		currentSession, err := tmux.GetCurrentSessionName()
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			fail("%s", err)
 			return
 		}
-		possibleDestinations := []string{}
-		for _, session := range existingSessions {
-			if session != currentSession {
-				possibleDestinations = append(possibleDestinations, session)
-			}
+
+		mode := workflow.RemoveSessionWorktree
+		if deleteBranch {
+			mode = workflow.RemoveSessionWorktreeAndBranch
+		}
+		currentSession, _ := tmux.GetCurrentSessionName()
+		opts := workflow.RemoveOptions{
+			Force:          force,
+			Archive:        archiveWork,
+			CurrentSession: currentSession,
+			Target:         targetSession,
+			Destinations:   cfg.Remove.Destinations,
 		}
 
-		// After
-		if len(possibleDestinations) == 0 {
-			color.Red("No available sessions to switch to")
-			return
+		p := plan.New(fmt.Sprintf("rm %s", branch))
+		if workspaceName != "" {
+			p = plan.New(fmt.Sprintf("rm --workspace %s %s", workspaceName, branch))
 		}
-		newSession := strings.ReplaceAll(possibleDestinations[0], "\"", "")
-		if !tmux.HasSession(newSession) {
-			color.Red("Session doesn't exist")
+		if err := workflow.AddRemoveSteps(p, session, mode, opts); err != nil {
+			fail("%s", err)
 			return
 		}
-		if nextBranch != "" {
-			tmux.SwitchToSession(targetSession)
-		} else {
-			needToSwitchSession := tmux.HasSession(sessionName) && currentSession == sessionName && len(possibleDestinations) > 0
-			if needToSwitchSession {
-				tmux.SwitchToSession(newSession)
-			}
+		if dryRun != "" {
+			printPlan(p, dryRun)
+			return
 		}
-		tmux.KillSession(sessionName)
 
-		// Unregister session from state
-		err = state.UnregisterSession(sessionName)
-		if err != nil {
-			color.Yellow("Warning: Failed to unregister session: %v", err)
+		if err := p.Execute(); err != nil {
+			printExecuteError(err)
 		}
 	},
 }
 
+// repoSession returns the session of branch in the current repo to remove.
+func repoSession(branch string) (state.SessionInfo, error) {
+	sessionName := utils.GenerateSessionNameFromBranch(branch)
+	worktreeName := utils.GenerateWorktreeNameFromBranch(branch)
+
+	// Resolved up front, it can't be once the worktree we're in is gone
+	baseDir, err := git.GetBaseDir()
+	if err != nil {
+		return state.SessionInfo{}, err
+	}
+
+	if !git.HasBranch(branch, true) {
+		return state.SessionInfo{}, fmt.Errorf("Branch %s doesn't exist, or isn't checked out", branch)
+	}
+	if !git.HasWorktree(branch) {
+		return state.SessionInfo{}, fmt.Errorf("Can't delete worktree %s as it doesn't exist", branch)
+	}
+
+	// Sessions twt didn't create are removed the same way, from where twt would put them
+	session, registered, err := state.GetSession(sessionName)
+	if err != nil || !registered {
+		session = state.SessionInfo{
+			Name:         sessionName,
+			RepoPath:     baseDir,
+			RepoName:     filepath.Base(baseDir),
+			Branch:       branch,
+			WorktreePath: filepath.Join(baseDir, worktreeName),
+		}
+	}
+	return session, nil
+}
+
+// workspaceSession returns the session of branch in the workspace to remove.
+func workspaceSession(workspaceName, branch string) (state.SessionInfo, error) {
+	workspace, err := workflow.LoadWorkspace(workspaceName)
+	if err != nil {
+		return state.SessionInfo{}, err
+	}
+	return workflow.WorkspaceSession(workspace, branch)
+}
+
 func init() {
 	rootCmd.AddCommand(removeWorktree)
 	removeWorktree.Flags().BoolP("delete-branch", "d", false, "Remove branch as well as the worktree")
 	removeWorktree.Flags().BoolP("force", "f", false, "Delete the worktree &| branch regardless of unstaged files")
+	removeWorktree.Flags().Bool("archive", false, "Archive uncommitted and untracked work before removing, defaults to on with --force (see rm.archive_on_force)")
 	removeWorktree.Flags().BoolP("confirm", "y", false, "Skip confirmation prompt")
-	removeWorktree.Flags().StringP("target", "t", "", "Where to go after removing session")
+	removeWorktree.Flags().StringP("workspace", "w", "", "Remove the branch's worktrees from every repo of the workspace, and its session")
+	addDryRunFlag(removeWorktree)
+	removeWorktree.Flags().StringP("target", "t", "", "Branch whose session to go to after removing the current session, instead of the configured destinations")
 }
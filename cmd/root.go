@@ -1,13 +1,55 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/workflow"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "twt",
 	Short: "Manage tmux sessions & windows based on Git worktrees.",
+	// With --repo, commands run from the base dir of that repo instead of the current dir
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		repo, err := cmd.Flags().GetString("repo")
+		if err != nil {
+			color.Red("Couldn't check repo flag")
+			os.Exit(1)
+		}
+		if repo == "" {
+			return
+		}
+
+		baseDir, err := workflow.ResolveRepo(repo)
+		if err != nil {
+			color.Red("%s", err)
+			os.Exit(1)
+		}
+		if err := os.Chdir(baseDir); err != nil {
+			color.Red("Couldn't change to %s: %s", baseDir, err)
+			os.Exit(1)
+		}
+	},
+}
+
+const (
+	// A command failed, and what it had done was rolled back
+	exitFailed = 1
+	// A command completed, but something allowed to fail did, e.g. a script whose
+	// on_failure is warn
+	exitWarnings = 2
+)
+
+// exitCode is what twt exits with once the command returns.
+var exitCode int
+
+// fail reports what stopped a command, or part of it, and makes twt exit with exitFailed.
+func fail(format string, a ...interface{}) {
+	color.Red(format, a...)
+	exitCode = exitFailed
 }
 
 func Execute() {
@@ -16,5 +58,11 @@ func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
 		color.Red("Error when running cmd.")
+		os.Exit(exitFailed)
 	}
+	os.Exit(exitCode)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("repo", "R", "", "Run against a registered repo alias or a repo path instead of the current dir")
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save [branch]",
+	Short: "Save the layout of twt sessions so they can be restored later.",
+	Long: `Record the windows, pane layout and working dirs of a twt session, so 'twt restore' or
+'twt go' can recreate it after the tmux server stops, e.g. after a reboot.
+
+Pane commands are only recorded if they're listed in "sessions.restore_commands" in the
+config file, e.g. editors. Defaults to the current session, use --all for every running
+twt session.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		all, err := flags.GetBool("all")
+		if err != nil {
+			fail("Couldn't check all flag")
+			return
+		}
+		quiet, err := flags.GetBool("quiet")
+		if err != nil {
+			fail("Couldn't check quiet flag")
+			return
+		}
+
+		dryRun, err := dryRunFormat(cmd)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+
+		cfg, err := config.Load()
+		if err != nil && !quiet {
+			color.Yellow("Warning: %v, using default restore commands", err)
+		}
+
+		if all {
+			p, sessions, err := workflow.PlanSaveAll(cfg.Sessions.RestoreCommands)
+			if err != nil {
+				exitCode = exitFailed
+				if !quiet {
+					color.Red("%s", err)
+				}
+				return
+			}
+			if dryRun != "" {
+				printPlan(p, dryRun)
+				return
+			}
+			results := workflow.ExecuteBulk(p, sessions)
+			if !quiet {
+				printSessionResults(results, "saved")
+			}
+			return
+		}
+
+		var sessionName string
+		if len(args) == 1 {
+			branch, err := command.Validate(args[0])
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			sessionName = utils.GenerateSessionNameFromBranch(branch)
+		} else {
+			sessionName, err = tmux.GetCurrentSessionName()
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+		}
+
+		if err := workflow.SaveSession(sessionName, cfg.Sessions.RestoreCommands); err != nil {
+			exitCode = exitFailed
+			if !quiet {
+				color.Red("%s", err)
+			}
+			return
+		}
+		if !quiet {
+			color.Green("Saved %s.", sessionName)
+		}
+	},
+}
+
+func printSessionResults(results []workflow.SessionResult, action string) {
+	if len(results) == 0 {
+		color.Yellow("No sessions to process.")
+		return
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			fail(" - %s: %s", result.Session.Name, result.Err)
+		} else {
+			color.Green(" - %s: %s", result.Session.Name, action)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(saveCmd)
+
+	saveCmd.Flags().BoolP("all", "a", false, "Save every running twt session")
+	addDryRunFlag(saveCmd)
+	saveCmd.Flags().BoolP("quiet", "q", false, "Print nothing, for use in hooks")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+var touchCmd = &cobra.Command{
+	Use:   "touch [session]",
+	Short: "Record that a session was just accessed.",
+	Long: `Mark a twt session as accessed now, for 'twt last', 'twt recent' and MRU sorting.
+Defaults to the current tmux session, and does nothing for sessions twt doesn't manage.
+
+Meant to be run by the tmux hooks set up with 'twt hooks install', so it prints nothing.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		var sessionName string
+		if len(args) == 1 {
+			sessionName = args[0]
+		} else {
+			current, err := tmux.GetCurrentSessionName()
+			if err != nil {
+				return
+			}
+			sessionName = current
+		}
+
+		state.UpdateLastAccessed(sessionName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(touchCmd)
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/provision"
+	"github.com/j-clemons/twt/internal/scripts"
+	"github.com/j-clemons/twt/internal/trust"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+var trustBase = &cobra.Command{
+	Use:   "trust",
+	Short: "Manage which hook scripts twt may run.",
+	Long: `twt only runs a repo's hook scripts, and the commands in its provision.json, once
+you've trusted them, as common files can come from a template or a shared repo. Trusting a
+script records a hash of its content, so when it changes you're shown a diff and asked
+again before it runs. Without a terminal to ask on, e.g. in CI, untrusted scripts fail the
+command instead.`,
+}
+
+var trustList = &cobra.Command{
+	Use:   "list",
+	Short: "List trusted scripts and whether they changed since.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := trust.List()
+		if err != nil {
+			fail("Error listing trusted scripts: %v", err)
+			return
+		}
+		if len(entries) == 0 {
+			color.Yellow("No trusted scripts.")
+			return
+		}
+
+		for _, entry := range entries {
+			status, _, err := trust.Check(entry.Path)
+			if err != nil {
+				fail("%s: %s", entry.Path, err)
+				continue
+			}
+			line := fmt.Sprintf("%-10s %s  %s  trusted %s ago", status, entry.Hash[:12], entry.Path, utils.FormatAge(time.Since(entry.AllowedAt)))
+			if status == trust.Trusted {
+				color.Green("%s", line)
+			} else {
+				color.Yellow("%s", line)
+			}
+		}
+	},
+}
+
+var trustAllow = &cobra.Command{
+	Use:   "allow [script]...",
+	Short: "Trust scripts as they are now, or every script of the current repo.",
+	Long: `Trust scripts as they are now. Scripts are paths, or names in the current repo's
+scripts dir like go/post.sh, or its provision.json. Without any, every script of the
+current repo and its provision.json are trusted. Use --diff to see what changed first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		showDiff, err := cmd.Flags().GetBool("diff")
+		if err != nil {
+			fail("Couldn't check diff flag")
+			return
+		}
+
+		var paths []string
+		if len(args) == 0 {
+			commonDir, err := utils.GetCommonFilesDirPath()
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if _, err := os.Stat(provision.Path(commonDir)); err == nil {
+				paths = append(paths, provision.Path(commonDir))
+			}
+			if scriptsDir, err := utils.GetScriptsDirPath(); err == nil {
+				found, err := scripts.DiscoverAll(scriptsDir)
+				if err != nil {
+					fail("%s", err)
+					return
+				}
+				for _, script := range found {
+					paths = append(paths, script.Path)
+				}
+			}
+		}
+		for _, arg := range args {
+			path, err := scriptPath(arg, true)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			paths = append(paths, path)
+		}
+
+		for _, path := range paths {
+			status, entry, err := trust.Check(path)
+			if err != nil {
+				fail("%s: %s", path, err)
+				return
+			}
+			if status == trust.Trusted {
+				color.White("%s is already trusted", path)
+				continue
+			}
+			if showDiff {
+				if diff, err := trust.Diff(path, entry); err == nil {
+					fmt.Println(diff)
+				}
+			}
+			if _, err := trust.Allow(path); err != nil {
+				fail("%s: %s", path, err)
+				return
+			}
+			color.Green("Trusted %s", path)
+		}
+	},
+}
+
+var trustRevoke = &cobra.Command{
+	Use:   "revoke <script>...",
+	Short: "Stop trusting scripts, so they're asked about again before running.",
+	Args:  cobra.MatchAll(cobra.MinimumNArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, arg := range args {
+			path, err := scriptPath(arg, false)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if err := trust.Revoke(path); err != nil {
+				fail("%s", err)
+				return
+			}
+			color.Green("Revoked trust in %s", path)
+		}
+	},
+}
+
+// scriptPath resolves a script given as a path, or as a name in the current repo's scripts
+// dir, or its provision.json. A script being revoked may no longer exist.
+func scriptPath(arg string, mustExist bool) (string, error) {
+	path, err := filepath.Abs(arg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if !mustExist {
+		if _, entry, err := trust.Check(path); err == nil && entry.Path != "" {
+			return path, nil
+		}
+	}
+
+	if arg == provision.FileName {
+		if commonDir, err := utils.GetCommonFilesDirPath(); err == nil {
+			return provision.Path(commonDir), nil
+		}
+	}
+	if scriptsDir, err := utils.GetScriptsDirPath(); err == nil {
+		inRepo := filepath.Join(scriptsDir, arg)
+		if _, err := os.Stat(inRepo); err == nil || !mustExist {
+			return inRepo, nil
+		}
+	}
+	return "", fmt.Errorf("no script %s", arg)
+}
+
+func init() {
+	rootCmd.AddCommand(trustBase)
+
+	trustBase.AddCommand(trustList)
+	trustBase.AddCommand(trustAllow)
+	trustBase.AddCommand(trustRevoke)
+
+	trustAllow.Flags().BoolP("diff", "d", false, "Show what's new or changed in each script before trusting it")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+var workspaceBase = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage workspaces, named sets of repos worked on together.",
+	Long: `A workspace is a named set of repos, e.g. an API, its frontend and shared protos, for
+features that span them. 'twt go --workspace <name> <branch>' creates the branch's worktree
+in each repo and opens one session with a window per repo; 'twt rm --workspace <name>
+<branch>' removes them all again.`,
+}
+
+var workspaceAdd = &cobra.Command{
+	Use:   "add <name> <repo>...",
+	Short: "Define a workspace from repo aliases or paths, redefining it if it exists.",
+	Args:  cobra.MatchAll(cobra.MinimumNArgs(2)),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		var repoPaths []string
+		seen := make(map[string]bool)
+		for _, repo := range args[1:] {
+			repoPath, err := workflow.ResolveRepo(repo)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			if !seen[repoPath] {
+				seen[repoPath] = true
+				repoPaths = append(repoPaths, repoPath)
+			}
+		}
+
+		workspace, err := state.SaveWorkspace(name, repoPaths)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		color.Green("Saved workspace %s with %s", workspace.Name, strings.Join(workspace.Repos, ", "))
+	},
+}
+
+var workspaceRemove = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Forget a workspace, leaving its worktrees and sessions alone.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := state.RemoveWorkspace(args[0]); err != nil {
+			fail("%s", err)
+			return
+		}
+		color.Green("Removed workspace %s", args[0])
+	},
+}
+
+var workspaceList = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces and their repos.",
+	Args:  cobra.MatchAll(cobra.ExactArgs(0)),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaces, err := state.ListWorkspaces()
+		if err != nil {
+			fail("Error listing workspaces: %v", err)
+			return
+		}
+		if len(workspaces) == 0 {
+			color.Yellow("No workspaces defined.")
+			return
+		}
+
+		for _, workspace := range workspaces {
+			fmt.Println(workspace.Name)
+			for _, repoPath := range workspace.Repos {
+				fmt.Printf("  %s\n", repoPath)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workspaceBase)
+
+	workspaceBase.AddCommand(workspaceAdd)
+	workspaceBase.AddCommand(workspaceRemove)
+	workspaceBase.AddCommand(workspaceList)
+}
@@ -0,0 +1,404 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/config"
+)
+
+const (
+	DirName = "archives"
+
+	metaFileName      = "meta.json"
+	stagedFileName    = "staged.patch"
+	unstagedFileName  = "unstaged.patch"
+	untrackedFileName = "untracked.tar.gz"
+
+	// Keeps the archived commits reachable, e.g. after the branch is deleted
+	refPrefix = "refs/twt/archive/"
+)
+
+// Archive is the uncommitted state of a worktree, saved before it's force removed.
+type Archive struct {
+	// Dir the archive files are in, also its id
+	Dir          string    `json:"-"`
+	SessionName  string    `json:"session_name"`
+	RepoPath     string    `json:"repo_path"`
+	Branch       string    `json:"branch"`
+	WorktreePath string    `json:"worktree_path"`
+	Head         string    `json:"head"`
+	Ref          string    `json:"ref"`
+	CreatedAt    time.Time `json:"created_at"`
+	// What was saved, so listing doesn't need to open the files
+	Staged    bool `json:"staged"`
+	Unstaged  bool `json:"unstaged"`
+	Untracked int  `json:"untracked"`
+}
+
+func (a Archive) Age() time.Duration {
+	return time.Since(a.CreatedAt)
+}
+
+func rootDir() (string, error) {
+	twtConfigDir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(twtConfigDir, DirName), nil
+}
+
+// Create saves the staged and unstaged changes, untracked files and HEAD of the worktree at
+// worktreePath. Nothing is changed in the worktree itself.
+func Create(sessionName, repoPath, branch, worktreePath string) (*Archive, error) {
+	root, err := rootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := command.Output(worktreePath, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%s-%s", sessionName, now.Format("20060102-150405"))
+	archive := &Archive{
+		Dir:          filepath.Join(root, id),
+		SessionName:  sessionName,
+		RepoPath:     repoPath,
+		Branch:       branch,
+		WorktreePath: worktreePath,
+		Head:         strings.TrimSpace(string(head)),
+		Ref:          refPrefix + id,
+		CreatedAt:    now,
+	}
+	if err := os.MkdirAll(archive.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	err = archive.save(worktreePath)
+	if err != nil {
+		os.RemoveAll(archive.Dir)
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *Archive) save(worktreePath string) error {
+	staged, err := command.Output(worktreePath, "git", "diff", "--cached", "--binary")
+	if err != nil {
+		return err
+	}
+	unstaged, err := command.Output(worktreePath, "git", "diff", "--binary")
+	if err != nil {
+		return err
+	}
+	a.Staged = len(staged) > 0
+	a.Unstaged = len(unstaged) > 0
+
+	if err := os.WriteFile(filepath.Join(a.Dir, stagedFileName), staged, 0644); err != nil {
+		return fmt.Errorf("failed to write patch: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(a.Dir, unstagedFileName), unstaged, 0644); err != nil {
+		return fmt.Errorf("failed to write patch: %w", err)
+	}
+
+	untracked, err := command.Output(worktreePath, "git", "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, file := range strings.Split(string(untracked), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	if err := writeTarball(filepath.Join(a.Dir, untrackedFileName), worktreePath, files); err != nil {
+		return fmt.Errorf("failed to archive untracked files: %w", err)
+	}
+	a.Untracked = len(files)
+
+	if _, err := command.Output(a.RepoPath, "git", "update-ref", a.Ref, a.Head); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(a.Dir, metaFileName), data, 0644)
+}
+
+// Apply recreates the saved changes in worktreePath. When it's checked out somewhere other
+// than the archived HEAD, e.g. as the branch moved on, they're merged in three-way against
+// it, which fails on conflicts.
+func (a Archive) Apply(worktreePath string) error {
+	head, err := command.Output(worktreePath, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	threeWay := strings.TrimSpace(string(head)) != a.Head
+
+	if a.Staged {
+		args := []string{"apply", "--index"}
+		if threeWay {
+			args = append(args, "--3way")
+		}
+		if _, err := command.Output(worktreePath, "git", append(args, filepath.Join(a.Dir, stagedFileName))...); err != nil {
+			return fmt.Errorf("couldn't apply staged changes: %w", err)
+		}
+	}
+	if a.Unstaged {
+		if err := a.applyUnstaged(worktreePath, threeWay); err != nil {
+			return fmt.Errorf("couldn't apply unstaged changes: %w", err)
+		}
+	}
+	if a.Untracked > 0 {
+		if err := extractTarball(filepath.Join(a.Dir, untrackedFileName), worktreePath); err != nil {
+			return fmt.Errorf("couldn't restore untracked files: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyUnstaged applies the unstaged changes to the worktree only. A three-way apply goes
+// through the index, so it's put back the way the staged changes left it.
+func (a Archive) applyUnstaged(worktreePath string, threeWay bool) error {
+	patch := filepath.Join(a.Dir, unstagedFileName)
+	if !threeWay {
+		_, err := command.Output(worktreePath, "git", "apply", patch)
+		return err
+	}
+
+	tree, err := command.Output(worktreePath, "git", "write-tree")
+	if err != nil {
+		return err
+	}
+	if _, err := command.Output(worktreePath, "git", "apply", "--3way", patch); err != nil {
+		return err
+	}
+	_, err = command.Output(worktreePath, "git", "read-tree", strings.TrimSpace(string(tree)))
+	return err
+}
+
+// Delete removes the archive files and the ref keeping its commits.
+func (a Archive) Delete() error {
+	command.Output(a.RepoPath, "git", "update-ref", "-d", a.Ref)
+	return os.RemoveAll(a.Dir)
+}
+
+// List returns the archives of the repo at repoPath, or of every repo if it's empty, newest
+// first.
+func List(repoPath string) ([]Archive, error) {
+	root, err := rootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, metaFileName))
+		if err != nil {
+			// Unfinished, or not an archive
+			continue
+		}
+		var archive Archive
+		if err := json.Unmarshal(data, &archive); err != nil {
+			continue
+		}
+		archive.Dir = dir
+		if repoPath == "" || archive.RepoPath == repoPath {
+			archives = append(archives, archive)
+		}
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].CreatedAt.After(archives[j].CreatedAt)
+	})
+	return archives, nil
+}
+
+// OrphanedRefs returns the refs keeping archived commits of the repo at repoPath that no
+// archive uses anymore, e.g. as its files were deleted by hand.
+func OrphanedRefs(repoPath string) ([]string, error) {
+	out, err := command.Output(repoPath, "git", "for-each-ref", "--format=%(refname)", refPrefix)
+	if err != nil {
+		return nil, err
+	}
+	archives, err := List(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, archive := range archives {
+		used[archive.Ref] = true
+	}
+
+	var orphaned []string
+	for _, ref := range strings.Fields(string(out)) {
+		if !used[ref] {
+			orphaned = append(orphaned, ref)
+		}
+	}
+	return orphaned, nil
+}
+
+// DeleteRef deletes a ref keeping archived commits, see OrphanedRefs.
+func DeleteRef(repoPath, ref string) error {
+	if !strings.HasPrefix(ref, refPrefix) {
+		return fmt.Errorf("%s isn't an archive ref", ref)
+	}
+	_, err := command.Output(repoPath, "git", "update-ref", "-d", ref)
+	return err
+}
+
+// Latest returns the newest archive of branch in the repo at repoPath.
+func Latest(repoPath, branch string) (Archive, bool, error) {
+	archives, err := List(repoPath)
+	if err != nil {
+		return Archive{}, false, err
+	}
+	for _, archive := range archives {
+		if archive.Branch == branch {
+			return archive, true, nil
+		}
+	}
+	return Archive{}, false, nil
+}
+
+func writeTarball(path, baseDir string, files []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		if err := addToTarball(tw, baseDir, file); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addToTarball(tw *tar.Writer, baseDir, file string) error {
+	path := filepath.Join(baseDir, file)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(file)
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(tw, src)
+	return err
+}
+
+func extractTarball(path, baseDir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(baseDir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(baseDir)+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to extract %s outside the worktree", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
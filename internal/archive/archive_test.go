@@ -0,0 +1,198 @@
+package archive_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/archive"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=twt", "GIT_AUTHOR_EMAIL=twt@example.com",
+		"GIT_COMMITTER_NAME=twt", "GIT_COMMITTER_EMAIL=twt@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %s: %s", args, err, out)
+	}
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %s", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Couldn't read %s: %s", path, err)
+	}
+	return string(data)
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("HOME", dir)
+
+	repo := filepath.Join(dir, "repo")
+	git(t, dir, "init", "-q", "-b", "main", repo)
+	writeFile(t, filepath.Join(repo, "staged.txt"), "one\n")
+	writeFile(t, filepath.Join(repo, "unstaged.txt"), "one\n")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "init")
+
+	worktree := filepath.Join(dir, "feature")
+	git(t, repo, "worktree", "add", "-q", "-b", "feature", worktree)
+	writeFile(t, filepath.Join(worktree, "staged.txt"), "two\n")
+	git(t, worktree, "add", "staged.txt")
+	writeFile(t, filepath.Join(worktree, "unstaged.txt"), "two\n")
+	writeFile(t, filepath.Join(worktree, "new/untracked.txt"), "new\n")
+
+	saved, err := archive.Create("repo_feature", repo, "feature", worktree)
+	if err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	if !saved.Staged || !saved.Unstaged || saved.Untracked != 1 {
+		t.Fatalf("Expected staged, unstaged and 1 untracked file but got %+v", saved)
+	}
+
+	git(t, repo, "worktree", "remove", "--force", worktree)
+	git(t, repo, "branch", "-D", "feature")
+
+	latest, ok, err := archive.Latest(repo, "feature")
+	if err != nil || !ok {
+		t.Fatalf("Expected to find the archive, got %v, %s", ok, err)
+	}
+
+	// The archive ref keeps the commit around after the branch is gone
+	git(t, repo, "worktree", "add", "-q", "-b", "feature", worktree, latest.Head)
+	if err := latest.Apply(worktree); err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+
+	for file, expected := range map[string]string{
+		"staged.txt":        "two\n",
+		"unstaged.txt":      "two\n",
+		"new/untracked.txt": "new\n",
+	} {
+		if got := readFile(t, filepath.Join(worktree, file)); got != expected {
+			t.Fatalf("%s: Expected %q but got %q", file, expected, got)
+		}
+	}
+
+	if err := latest.Delete(); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if archives, _ := archive.List(""); len(archives) != 0 {
+		t.Fatalf("Expected no archives after delete but got %d", len(archives))
+	}
+}
+
+// archiveFeature archives a feature worktree with a staged and an unstaged change, and
+// removes it, keeping the branch.
+func archiveFeature(t *testing.T) (repo, worktree string, saved *archive.Archive) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("HOME", dir)
+
+	repo = filepath.Join(dir, "repo")
+	git(t, dir, "init", "-q", "-b", "main", repo)
+	writeFile(t, filepath.Join(repo, "staged.txt"), "1\n2\n3\n4\n")
+	writeFile(t, filepath.Join(repo, "unstaged.txt"), "one\n")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "init")
+
+	worktree = filepath.Join(dir, "feature")
+	git(t, repo, "worktree", "add", "-q", "-b", "feature", worktree)
+	writeFile(t, filepath.Join(worktree, "staged.txt"), "one\n2\n3\n4\n")
+	git(t, worktree, "add", "staged.txt")
+	writeFile(t, filepath.Join(worktree, "unstaged.txt"), "two\n")
+
+	saved, err := archive.Create("repo_feature", repo, "feature", worktree)
+	if err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	git(t, repo, "worktree", "remove", "--force", worktree)
+	return repo, worktree, saved
+}
+
+func TestApplyAfterBranchMoved(t *testing.T) {
+	cases := []struct {
+		name            string
+		changedFile     string
+		changed         string
+		expectedSuccess bool
+		expectedStaged  string
+	}{
+		{name: "Other file changed", changedFile: "other.txt", changed: "moved on\n", expectedSuccess: true, expectedStaged: "one\n2\n3\n4\n"},
+		{name: "Next to the staged change", changedFile: "staged.txt", changed: "1\n2\nthree\n4\n", expectedSuccess: true, expectedStaged: "one\n2\nthree\n4\n"},
+		{name: "Staged change conflicts", changedFile: "staged.txt", changed: "uno\n2\n3\n4\n", expectedSuccess: false},
+	}
+
+	for _, c := range cases {
+		repo, worktree, saved := archiveFeature(t)
+		git(t, repo, "worktree", "add", "-q", worktree, "feature")
+		writeFile(t, filepath.Join(worktree, c.changedFile), c.changed)
+		git(t, worktree, "add", c.changedFile)
+		git(t, worktree, "commit", "-q", "-m", "moved on")
+
+		err := saved.Apply(worktree)
+		if c.expectedSuccess != (err == nil) {
+			t.Fatalf("%s: Expected success %t but got error: %v", c.name, c.expectedSuccess, err)
+		}
+		if !c.expectedSuccess {
+			continue
+		}
+		for file, expected := range map[string]string{"staged.txt": c.expectedStaged, "unstaged.txt": "two\n"} {
+			if got := readFile(t, filepath.Join(worktree, file)); got != expected {
+				t.Fatalf("%s: Expected %s to be %q but got %q", c.name, file, expected, got)
+			}
+		}
+		if staged := gitOutput(t, worktree, "diff", "--cached", "--name-only"); staged != "staged.txt" {
+			t.Fatalf("%s: Expected only staged.txt staged but got %q", c.name, staged)
+		}
+	}
+}
+
+func TestOrphanedRefs(t *testing.T) {
+	repo, _, saved := archiveFeature(t)
+	orphaned, err := archive.OrphanedRefs(repo)
+	if err != nil || len(orphaned) != 0 {
+		t.Fatalf("With the archive: Expected no orphaned refs but got %v, %v", orphaned, err)
+	}
+
+	if err := os.RemoveAll(saved.Dir); err != nil {
+		t.Fatal(err)
+	}
+	orphaned, err = archive.OrphanedRefs(repo)
+	if err != nil || len(orphaned) != 1 || orphaned[0] != saved.Ref {
+		t.Fatalf("Without the archive: Expected %s orphaned but got %v, %v", saved.Ref, orphaned, err)
+	}
+
+	if err := archive.DeleteRef(repo, "refs/heads/main"); err == nil {
+		t.Fatalf("Deleting a branch: Expected error but got success")
+	}
+	if err := archive.DeleteRef(repo, saved.Ref); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if orphaned, _ := archive.OrphanedRefs(repo); len(orphaned) != 0 {
+		t.Fatalf("After deleting: Expected no orphaned refs but got %v", orphaned)
+	}
+}
@@ -3,6 +3,7 @@ package checks
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 
 	"github.com/fatih/color"
 )
@@ -14,6 +15,15 @@ func AssertTmux() error {
 	return nil
 }
 
+// AssertTmuxInstalled checks tmux can be run, for commands that start or attach to sessions
+// from outside tmux too.
+func AssertTmuxInstalled() error {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return errors.New("\u2717 tmux isn't installed")
+	}
+	return nil
+}
+
 func AssertGit() error {
 	isWorktree := IsInWorktree()
 	inGitDir := InGitDir()
@@ -32,7 +42,7 @@ func AssertReady() bool {
 	shouldCancel := false
 
 	gitErr := AssertGit()
-	tmuxErr := AssertTmux()
+	tmuxErr := AssertTmuxInstalled()
 	errs := [2]error{gitErr, tmuxErr}
 
 	for _, err := range errs {
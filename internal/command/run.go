@@ -1,8 +1,10 @@
 package command
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"os/exec"
 	"strings"
 
 	"github.com/go-cmd/cmd"
@@ -38,3 +40,18 @@ func Validate(branchName string) (string, error) {
 	}
 	return branchName, nil
 }
+
+// Output runs app in dir and returns its stdout unchanged. Use it over Run when the output
+// must be kept byte for byte, e.g. patches, rather than split into lines.
+func Output(dir, app string, args ...string) ([]byte, error) {
+	c := exec.Command(app, args...)
+	c.Dir = dir
+
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w: %s", app, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package compose
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+// EnvVar is how compose is told which project it's working on.
+const EnvVar = "COMPOSE_PROJECT_NAME"
+
+// Files are the compose files compose picks up in a dir, in its order of preference.
+var Files = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ProjectName returns the compose project of branch's worktree in the repo at repoPath. It's
+// its session name made of what compose allows, lowercase letters, digits, - and _, so every
+// worktree gets its own containers, networks and volumes, and the same ones each time. When
+// the session name had to change, a hash of it is appended, so e.g. a.b and a-b don't share
+// a project.
+func ProjectName(repoPath, branch string) string {
+	sessionName := utils.GenerateSessionNameForRepo(repoPath, branch)
+
+	var name strings.Builder
+	for _, r := range strings.ToLower(sessionName) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			name.WriteRune(r)
+		default:
+			name.WriteRune('-')
+		}
+	}
+	// It has to start with a letter or digit
+	project := strings.TrimLeft(name.String(), "_-")
+	if project == sessionName {
+		return project
+	}
+	sum := sha256.Sum256([]byte(sessionName))
+	return project + "-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// SplitArgs splits twt compose's arguments into the branch and compose's own arguments,
+// which have to come after --. dash is where -- was in args, or -1 without one.
+func SplitArgs(args []string, dash int) (branch string, composeArgs []string, err error) {
+	if len(args) == 0 || dash == 0 || (len(args) > 1 && dash != 1) {
+		return "", nil, fmt.Errorf("Give compose's arguments after --, e.g. twt compose feature -- up -d")
+	}
+	return args[0], args[1:], nil
+}
+
+// Env returns the env var setting the compose project.
+func Env(project string) string {
+	return EnvVar + "=" + project
+}
+
+// HasFile reports whether dir has a compose file.
+func HasFile(dir string) bool {
+	for _, file := range Files {
+		if info, err := os.Stat(filepath.Join(dir, file)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// Available reports whether the configured compose command, e.g. docker compose, is
+// installed.
+func Available(command []string) bool {
+	if len(command) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(command[0])
+	return err == nil
+}
+
+// Projects returns the names of the compose projects that have containers, running or not.
+// Fails when compose can't list them, e.g. as its daemon isn't running.
+func Projects(command []string) ([]string, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no compose command configured")
+	}
+	args := append(slices.Clone(command[1:]), "ls", "--all", "--quiet")
+	cmd := exec.Command(command[0], args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s ls failed: %w: %s", strings.Join(command, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Run runs the compose command with args in dir, as the project. Ctrl-C reaches compose
+// itself, as it's in the foreground, so twt waits for it to exit rather than dying first.
+func Run(command []string, project, dir string, args ...string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no compose command configured")
+	}
+	cmd := exec.Command(command[0], append(slices.Clone(command[1:]), args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), Env(project))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	return cmd.Run()
+}
@@ -0,0 +1,101 @@
+package compose_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/compose"
+)
+
+func TestProjectName(t *testing.T) {
+	cases := []struct {
+		name     string
+		repoPath string
+		branch   string
+		expected string
+	}{
+		{name: "Plain branch", repoPath: "/code/api.git", branch: "main", expected: "api_git_main"},
+		{name: "Nested branch", repoPath: "/code/api.git", branch: "feature/login", expected: "api_git_feature__login"},
+		{name: "Upper case", repoPath: "/code/API", branch: "Fix-Bug", expected: "api_fix-bug-844286e8"},
+		{name: "Other characters", repoPath: "/code/.web+ui", branch: "v1.2@rc", expected: "web-ui_v1-2-rc-56d827e4"},
+		{name: "Dotted branch", repoPath: "/code/api", branch: "a.b", expected: "api_a-b-5eabdd18"},
+		{name: "Dashed branch", repoPath: "/code/api", branch: "a-b", expected: "api_a-b"},
+	}
+
+	for _, c := range cases {
+		if name := compose.ProjectName(c.repoPath, c.branch); name != c.expected {
+			t.Fatalf("%s: Expected %s but got %s", c.name, c.expected, name)
+		}
+	}
+}
+
+func TestHasFile(t *testing.T) {
+	dir := t.TempDir()
+	if compose.HasFile(dir) {
+		t.Fatalf("Empty dir: Expected no compose file")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "compose.yaml"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if compose.HasFile(dir) {
+		t.Fatalf("Dir named compose.yaml: Expected no compose file")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !compose.HasFile(dir) {
+		t.Fatalf("docker-compose.yml: Expected a compose file")
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		name            string
+		args            []string
+		dash            int
+		expectedBranch  string
+		expectedArgs    []string
+		expectedSuccess bool
+	}{
+		{name: "Branch only", args: []string{"feature"}, dash: -1, expectedBranch: "feature", expectedSuccess: true},
+		{name: "Arguments after --", args: []string{"feature", "up", "-d"}, dash: 1, expectedBranch: "feature", expectedArgs: []string{"up", "-d"}, expectedSuccess: true},
+		{name: "Nothing after --", args: []string{"feature"}, dash: 1, expectedBranch: "feature", expectedSuccess: true},
+		{name: "Arguments without --", args: []string{"feature", "ps"}, dash: -1, expectedSuccess: false},
+		{name: "Two arguments before --", args: []string{"feature", "ps", "api"}, dash: 2, expectedSuccess: false},
+		{name: "No branch before --", args: []string{"up"}, dash: 0, expectedSuccess: false},
+	}
+
+	for _, c := range cases {
+		branch, args, err := compose.SplitArgs(c.args, c.dash)
+		if c.expectedSuccess && err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if !c.expectedSuccess {
+			if err == nil {
+				t.Fatalf("%s: Expected error but got success", c.name)
+			}
+			continue
+		}
+		if branch != c.expectedBranch {
+			t.Fatalf("%s: Expected branch %s but got %s", c.name, c.expectedBranch, branch)
+		}
+		if strings.Join(args, " ") != strings.Join(c.expectedArgs, " ") {
+			t.Fatalf("%s: Expected args %v but got %v", c.name, c.expectedArgs, args)
+		}
+	}
+}
+
+func TestProjects(t *testing.T) {
+	projects, err := compose.Projects([]string{"sh", "-c", "echo api_git_main; echo web_feature", "sh"})
+	if err != nil {
+		t.Fatalf("Listing: Expected success but got error: %s", err)
+	}
+	if strings.Join(projects, " ") != "api_git_main web_feature" {
+		t.Fatalf("Listing: Expected api_git_main web_feature but got %v", projects)
+	}
+	if _, err := compose.Projects([]string{"sh", "-c", "echo 'Cannot connect to the Docker daemon' >&2; exit 1", "sh"}); err == nil {
+		t.Fatalf("Daemon down: Expected error but got success")
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	DirName        = "twt"
+	ConfigFileName = "config.json"
+)
+
+type Config struct {
+	Theme     Theme           `json:"theme"`
+	Keys      KeyMap          `json:"keys"`
+	List      ListConfig      `json:"list"`
+	Remove    RemoveConfig    `json:"rm"`
+	Sessions  SessionConfig   `json:"sessions"`
+	Discovery DiscoveryConfig `json:"discovery"`
+	Common    CommonConfig    `json:"common"`
+	Scripts   ScriptsConfig   `json:"scripts"`
+	Compose   ComposeConfig   `json:"compose"`
+}
+
+type ComposeConfig struct {
+	// Export a COMPOSE_PROJECT_NAME of its own to each worktree's session and scripts, and
+	// bring the project down when the worktree is removed
+	Enabled bool `json:"enabled"`
+	// How compose is run, e.g. ["docker-compose"] or ["podman", "compose"]
+	Command []string `json:"command"`
+	// Arguments to compose down when a worktree is removed, e.g. ["--volumes"]
+	DownArgs []string `json:"down_args"`
+}
+
+type ScriptsConfig struct {
+	// Whether scripts run, by name relative to the scripts dir or glob pattern, e.g.
+	// {"go/post.d/20-npm.sh": false}. Scripts run unless disabled here.
+	Enabled map[string]bool `json:"enabled"`
+	// How every script runs
+	ScriptSettings
+	// Settings for scripts by name or glob pattern, overriding those above, e.g.
+	// {"go/post.d/20-npm.sh": {"timeout": "30m"}}
+	PerScript map[string]ScriptSettings `json:"per_script"`
+}
+
+// ScriptSettings are how a script runs. Unset settings fall back to the defaults.
+type ScriptSettings struct {
+	// How long a script may run before it and everything it started are killed, e.g. "5m",
+	// or "0" for no limit
+	Timeout string `json:"timeout,omitempty"`
+	// Whether scripts inherit twt's environment or get a clean one, see ScriptEnvs. TWT_*
+	// vars describing the worktree are set either way.
+	Env string `json:"env,omitempty"`
+	// Where scripts run, see ScriptDirs
+	Dir string `json:"dir,omitempty"`
+	// What a failing script does to the command running it, see FailurePolicies. Unset, it
+	// depends on the script's phase, see DefaultOnFailure.
+	OnFailure string `json:"on_failure,omitempty"`
+}
+
+const (
+	EnvInherit = "inherit"
+	// Only PATH, HOME, USER, SHELL, TERM, LANG and TMPDIR are kept
+	EnvClean = "clean"
+)
+
+var ScriptEnvs = []string{EnvInherit, EnvClean}
+
+const (
+	DirWorktree = "worktree"
+	// The repo's base dir, i.e. the bare repo
+	DirRepo   = "repo"
+	DirCommon = "common"
+)
+
+var ScriptDirs = []string{DirWorktree, DirRepo, DirCommon}
+
+const (
+	// Roll the command back and exit 1
+	FailureAbort = "abort"
+	// Carry on, and exit 2 once done
+	FailureWarn = "warn"
+	// Carry on as if it succeeded
+	FailureIgnore = "ignore"
+)
+
+var FailurePolicies = []string{FailureAbort, FailureWarn, FailureIgnore}
+
+// DefaultOnFailure is what a failing script of the phase does unless on_failure says
+// otherwise. A post script runs once the worktree and session are there, so it only warns
+// rather than rolling them back. Any other script aborts.
+func DefaultOnFailure(phase string) string {
+	if phase == "post" {
+		return FailureWarn
+	}
+	return FailureAbort
+}
+
+type CommonConfig struct {
+	// User templates for 'twt common init --template', by name. Each is a dir, or a git repo
+	// whose committed files are used.
+	Templates map[string]string `json:"templates"`
+}
+
+type DiscoveryConfig struct {
+	// Dirs searched for bare repos by 'twt projects', e.g. "~/code"
+	Roots []string `json:"roots"`
+	// How many dirs below each root are searched
+	MaxDepth int `json:"max_depth"`
+	// Names of dirs not searched, as glob patterns
+	Ignore []string `json:"ignore"`
+	// How long the index of found projects is used before searching again, e.g. "1h" or "1d"
+	RefreshAfter string `json:"refresh_after"`
+}
+
+type SessionConfig struct {
+	// Commands rerun in their pane when a saved session is restored. Anything else, e.g. a
+	// shell or dev server, is restored as a plain shell in the same dir.
+	RestoreCommands []string `json:"restore_commands"`
+}
+
+type RemoveConfig struct {
+	// Where rm switches to when removing the current session, tried in order. See
+	// DestinationPolicies.
+	Destinations []string `json:"destinations"`
+	// Archive uncommitted and untracked work before a forced removal, so it can be brought
+	// back with restore
+	ArchiveOnForce bool `json:"archive_on_force"`
+}
+
+const (
+	DestinationPrevious      = "previous"
+	DestinationRecent        = "recent"
+	DestinationDefaultBranch = "default-branch"
+	DestinationBase          = "base"
+)
+
+var DestinationPolicies = []string{DestinationPrevious, DestinationRecent, DestinationDefaultBranch, DestinationBase}
+
+// Dir returns the twt config directory, creating it if needed.
+func Dir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+
+	twtConfigDir := filepath.Join(configDir, DirName)
+	if err := os.MkdirAll(twtConfigDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return twtConfigDir, nil
+}
+
+func Default() *Config {
+	return &Config{
+		Theme: DefaultTheme(),
+		Keys:  DefaultKeyMap(),
+		List:  ListConfig{Sort: SortCreated},
+		Remove: RemoveConfig{
+			Destinations:   DestinationPolicies,
+			ArchiveOnForce: true,
+		},
+		Sessions: SessionConfig{
+			RestoreCommands: []string{"vim", "nvim", "htop", "top", "less", "man", "lazygit", "tig"},
+		},
+		Scripts: ScriptsConfig{
+			ScriptSettings: ScriptSettings{
+				Timeout: "10m",
+				Env:     EnvInherit,
+				Dir:     DirWorktree,
+			},
+		},
+		Compose: ComposeConfig{
+			Enabled:  true,
+			Command:  []string{"docker", "compose"},
+			DownArgs: []string{"--remove-orphans"},
+		},
+		Discovery: DiscoveryConfig{
+			MaxDepth:     3,
+			Ignore:       []string{"node_modules", "vendor", "target", ".cache"},
+			RefreshAfter: "1h",
+		},
+	}
+}
+
+// Load reads the config file. Missing settings, or a missing file, fall back to defaults.
+func Load() (*Config, error) {
+	config := Default()
+
+	dir, err := Dir()
+	if err != nil {
+		return config, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFileName))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return Default(), fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return config, nil
+}
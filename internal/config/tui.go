@@ -0,0 +1,94 @@
+package config
+
+const (
+	SortCreated = "created"
+	SortRecent  = "recent"
+)
+
+type ListConfig struct {
+	// Initial sort order of the session list, SortCreated or SortRecent
+	Sort string `json:"sort"`
+}
+
+type Theme struct {
+	HighlightBackground string `json:"highlight_background"`
+	HighlightForeground string `json:"highlight_foreground"`
+	Header              string `json:"header"`
+	Muted               string `json:"muted"`
+	Warning             string `json:"warning"`
+	Hint                string `json:"hint"`
+	Border              string `json:"border"`
+}
+
+// KeyMap holds the keys bound to each list action. An action can have several keys.
+type KeyMap struct {
+	Up       []string `json:"up"`
+	Down     []string `json:"down"`
+	PageUp   []string `json:"page_up"`
+	PageDown []string `json:"page_down"`
+	Top      []string `json:"top"`
+	Bottom   []string `json:"bottom"`
+	Select   []string `json:"select"`
+	Preview  []string `json:"preview"`
+	Sort     []string `json:"sort"`
+	Quit     []string `json:"quit"`
+	// Bulk operations on marked sessions
+	Mark           []string `json:"mark"`
+	Visual         []string `json:"visual"`
+	Cancel         []string `json:"cancel"`
+	KillSession    []string `json:"kill_session"`
+	RemoveWorktree []string `json:"remove_worktree"`
+	RemoveBranch   []string `json:"remove_worktree_and_branch"`
+	// Answers on the bulk confirmation
+	Confirm     []string `json:"confirm"`
+	ToggleForce []string `json:"toggle_force"`
+	Decline     []string `json:"decline"`
+}
+
+func DefaultTheme() Theme {
+	return Theme{
+		HighlightBackground: "#7D56F4",
+		HighlightForeground: "#FFFFFF",
+		Header:              "#FAFAFA",
+		Muted:               "8",
+		Warning:             "3",
+		Hint:                "6",
+		Border:              "8",
+	}
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:       []string{"up", "k"},
+		Down:     []string{"down", "j"},
+		PageUp:   []string{"pgup", "ctrl+u"},
+		PageDown: []string{"pgdown", "ctrl+d"},
+		Top:      []string{"home", "g"},
+		Bottom:   []string{"end", "G"},
+		Select:   []string{"enter"},
+		Preview:  []string{"p"},
+		Sort:     []string{"s"},
+		Quit:     []string{"q"},
+
+		Mark:           []string{" "},
+		Visual:         []string{"v"},
+		Cancel:         []string{"esc"},
+		KillSession:    []string{"x"},
+		RemoveWorktree: []string{"d"},
+		RemoveBranch:   []string{"D"},
+
+		Confirm:     []string{"y", "enter"},
+		ToggleForce: []string{"f"},
+		Decline:     []string{"n"},
+	}
+}
+
+// Matches reports whether key is bound to the action.
+func Matches(key string, bindings []string) bool {
+	for _, binding := range bindings {
+		if key == binding {
+			return true
+		}
+	}
+	return false
+}
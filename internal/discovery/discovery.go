@@ -0,0 +1,255 @@
+package discovery
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/fsutil"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+const IndexFileName = "projects.json"
+
+// How many repos are inspected at once while scanning
+const inspectWorkers = 8
+
+// Project is a bare repo found under one of the discovery roots.
+type Project struct {
+	Path     string   `json:"path"`
+	Name     string   `json:"name"`
+	Branches []string `json:"branches"`
+	// Branches checked out in a worktree
+	Worktrees map[string]string `json:"worktrees"`
+}
+
+// Index is the cached result of a scan.
+type Index struct {
+	ScannedAt time.Time `json:"scanned_at"`
+	// What was scanned, so a change to the settings rescans
+	Roots    []string  `json:"roots"`
+	MaxDepth int       `json:"max_depth"`
+	Ignore   []string  `json:"ignore"`
+	Projects []Project `json:"projects"`
+}
+
+// Load returns the cached index if it's for the same roots, depth and ignore patterns and
+// not older than RefreshAfter, otherwise scans the roots and caches the result. refresh
+// forces a scan.
+func Load(cfg config.DiscoveryConfig, refresh bool) (Index, error) {
+	roots, err := expandRoots(cfg.Roots)
+	if err != nil {
+		return Index{}, err
+	}
+	if len(roots) == 0 {
+		return Index{}, errors.New("No discovery roots configured - add discovery.roots to the config")
+	}
+
+	indexFile, err := indexPath()
+	if err != nil {
+		return Index{}, err
+	}
+	if !refresh {
+		maxAge, err := utils.ParseDuration(cfg.RefreshAfter)
+		if err != nil {
+			return Index{}, fmt.Errorf("discovery.refresh_after: %w", err)
+		}
+		if cached, err := readIndex(indexFile); err == nil &&
+			slices.Equal(cached.Roots, roots) && cached.MaxDepth == cfg.MaxDepth &&
+			slices.Equal(cached.Ignore, cfg.Ignore) && time.Since(cached.ScannedAt) < maxAge {
+			return cached, nil
+		}
+	}
+
+	index := Index{
+		ScannedAt: time.Now(),
+		Roots:     roots,
+		MaxDepth:  cfg.MaxDepth,
+		Ignore:    cfg.Ignore,
+		Projects:  Scan(roots, cfg.MaxDepth, cfg.Ignore),
+	}
+	return index, writeIndex(indexFile, index)
+}
+
+// Scan searches the roots for bare repos, at most maxDepth dirs down. Dirs whose names match
+// an ignore pattern aren't searched, nor are the insides of repos. Roots are walked, and the
+// repos found inspected, concurrently.
+func Scan(roots []string, maxDepth int, ignore []string) []Project {
+	repos := make(chan string)
+	var walkers sync.WaitGroup
+	for _, root := range roots {
+		walkers.Add(1)
+		go func() {
+			defer walkers.Done()
+			walk(root, maxDepth, ignore, repos)
+		}()
+	}
+	go func() {
+		walkers.Wait()
+		close(repos)
+	}()
+
+	var (
+		mu       sync.Mutex
+		projects []Project
+		seen     = make(map[string]bool)
+		workers  sync.WaitGroup
+	)
+	for range inspectWorkers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for repo := range repos {
+				project, err := Inspect(repo)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				if !seen[project.Path] {
+					seen[project.Path] = true
+					projects = append(projects, project)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	workers.Wait()
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Path < projects[j].Path
+	})
+	return projects
+}
+
+func walk(dir string, depth int, ignore []string, repos chan<- string) {
+	if isBareRepo(dir) {
+		repos <- dir
+		return
+	}
+	if depth <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || ignored(entry.Name(), ignore) {
+			continue
+		}
+		walk(filepath.Join(dir, entry.Name()), depth-1, ignore, repos)
+	}
+}
+
+func ignored(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isBareRepo checks the layout and config of dir rather than asking git, as it's called for
+// every dir searched.
+func isBareRepo(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+
+	file, err := os.Open(filepath.Join(dir, "config"))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found && strings.TrimSpace(key) == "bare" {
+			return strings.TrimSpace(value) == "true"
+		}
+	}
+	return false
+}
+
+// Inspect reads the branches and worktrees of the repo at path.
+func Inspect(path string) (Project, error) {
+	branches, err := git.ListBranches(path)
+	if err != nil {
+		return Project{}, err
+	}
+	worktrees, err := git.ListWorktrees(path)
+	if err != nil {
+		return Project{}, err
+	}
+
+	project := Project{
+		Path:      path,
+		Name:      strings.TrimSuffix(filepath.Base(path), ".git"),
+		Branches:  branches,
+		Worktrees: make(map[string]string),
+	}
+	for _, worktree := range worktrees {
+		if worktree.Branch != "" && !worktree.Bare {
+			project.Worktrees[worktree.Branch] = worktree.Path
+		}
+	}
+	return project, nil
+}
+
+func expandRoots(roots []string) ([]string, error) {
+	expanded := make([]string, 0, len(roots))
+	for _, root := range roots {
+		root, err := utils.ExpandHome(root)
+		if err != nil {
+			return nil, err
+		}
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, abs)
+	}
+	return expanded, nil
+}
+
+func indexPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, IndexFileName), nil
+}
+
+func readIndex(path string) (Index, error) {
+	var index Index
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return index, err
+	}
+	err = json.Unmarshal(data, &index)
+	return index, err
+}
+
+// writeIndex replaces the index in one go, so a concurrent Load never reads half of it.
+func writeIndex(path string, index Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0600)
+}
@@ -0,0 +1,75 @@
+package discovery_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/discovery"
+)
+
+func bareRepo(t *testing.T, path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q", "--bare", path).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s: %s", err, out)
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	bareRepo(t, filepath.Join(root, "work", "api.git"))
+	bareRepo(t, filepath.Join(root, "web"))
+	bareRepo(t, filepath.Join(root, "node_modules", "dep.git"))
+	bareRepo(t, filepath.Join(root, "a", "b", "c", "deep.git"))
+	// Repos inside a found repo aren't searched for
+	bareRepo(t, filepath.Join(root, "web", "nested.git"))
+
+	projects := discovery.Scan([]string{root}, 2, []string{"node_modules"})
+
+	expected := []string{filepath.Join(root, "web"), filepath.Join(root, "work", "api.git")}
+	if len(projects) != len(expected) {
+		t.Fatalf("Expected %d projects but got %+v", len(expected), projects)
+	}
+	for i, project := range projects {
+		if project.Path != expected[i] {
+			t.Fatalf("Expected project %d to be %s but got %s", i, expected[i], project.Path)
+		}
+	}
+	if projects[1].Name != "api" {
+		t.Fatalf("Expected name api but got %s", projects[1].Name)
+	}
+}
+
+func TestLoadRescansWhenSettingsChange(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	bareRepo(t, filepath.Join(root, "api.git"))
+	bareRepo(t, filepath.Join(root, "team", "web.git"))
+	bareRepo(t, filepath.Join(root, "vendor", "dep.git"))
+
+	cfg := config.DiscoveryConfig{Roots: []string{root}, MaxDepth: 1, RefreshAfter: "1h"}
+	cases := []struct {
+		name     string
+		maxDepth int
+		ignore   []string
+		expected int
+	}{
+		{name: "First scan", maxDepth: 1, expected: 1},
+		{name: "Deeper", maxDepth: 2, expected: 3},
+		{name: "Ignoring vendor", maxDepth: 2, ignore: []string{"vendor"}, expected: 2},
+	}
+	for _, c := range cases {
+		cfg.MaxDepth, cfg.Ignore = c.maxDepth, c.ignore
+		index, err := discovery.Load(cfg, false)
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if len(index.Projects) != c.expected {
+			t.Fatalf("%s: Expected %d projects but got %+v", c.name, c.expected, index.Projects)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package fsutil has the file handling shared by twt's stores: advisory locks and writes
+// that replace a file in one go.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// LockFileSuffix is appended to a file's path to name its lock file.
+const LockFileSuffix = ".lock"
+
+// WithLock holds an advisory lock on the file at path while fn runs, through a lock file
+// next to it created with perm. Use syscall.LOCK_SH for reads and syscall.LOCK_EX for
+// read-modify-write transactions.
+func WithLock(path string, perm os.FileMode, how int, fn func() error) error {
+	lockFile, err := os.OpenFile(path+LockFileSuffix, os.O_CREATE|os.O_RDWR, perm)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file of %s: %w", filepath.Base(path), err)
+	}
+	defer lockFile.Close()
+
+	if err := syscall.Flock(int(lockFile.Fd()), how); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+	defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+// WriteFileAtomic replaces the file at path with data, written to a temp file next to it,
+// synced and renamed over it, so readers never see half of it and a crash never loses both.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,50 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/fsutil"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	for _, data := range []string{"first", "second"} {
+		if err := fsutil.WriteFileAtomic(path, []byte(data), 0600); err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", data, err)
+		}
+		written, err := os.ReadFile(path)
+		if err != nil || string(written) != data {
+			t.Fatalf("%s: Expected %s but got %q (%v)", data, data, written, err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected mode 0600 but got %o", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("Expected no temp files left behind but got %d entries", len(entries))
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	ran := false
+	err := fsutil.WithLock(path, 0600, syscall.LOCK_EX, func() error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("Expected fn to run under the lock but got %v, ran %t", err, ran)
+	}
+	if _, err := os.Stat(path + fsutil.LockFileSuffix); err != nil {
+		t.Fatalf("Expected a lock file next to the store but got %s", err)
+	}
+}
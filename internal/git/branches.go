@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -35,6 +36,29 @@ func HasBranch(branch string, checkedOut bool) bool {
 	return ok
 }
 
+// HasBranchInRepo reports whether branch exists locally in the repo at repoPath.
+func HasBranchInRepo(repoPath, branch string) bool {
+	out, _ := command.Run("git", inRepo(repoPath, "show-ref", fmt.Sprintf("refs/heads/%s", branch))...)
+	return len(out) > 0
+}
+
+// BranchTip returns the commit branch points to in the repo at repoPath.
+func BranchTip(repoPath, branch string) (string, error) {
+	out, err := command.Output("", "git", inRepo(repoPath, "rev-parse", "--verify", "refs/heads/"+branch)...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CreateBranch creates branch at startPoint in the repo at repoPath, without checking it out.
+func CreateBranch(repoPath, branch, startPoint string) error {
+	if _, stderr := command.Run("git", inRepo(repoPath, "branch", branch, startPoint)...); len(stderr) > 0 {
+		return fmt.Errorf("couldn't create branch %s: %v", branch, stderr)
+	}
+	return nil
+}
+
 func HasWorktree(branch string) bool {
 	grepCmd := fmt.Sprintf("git worktree list | grep %s", branch)
 	out, _ := command.Run("bash", "-c", grepCmd)
@@ -42,6 +66,10 @@ func HasWorktree(branch string) bool {
 }
 
 func DeleteBranch(branch string, force bool) {
+	DeleteBranchFromRepo("", branch, force)
+}
+
+func DeleteBranchFromRepo(repoPath, branch string, force bool) []string {
 	app := "git"
 
 	deleteFlag := "-d"
@@ -49,6 +77,34 @@ func DeleteBranch(branch string, force bool) {
 		deleteFlag = "-D"
 	}
 
-	args := []string{"branch", deleteFlag, branch}
-	command.Run(app, args...)
+	args := inRepo(repoPath, "branch", deleteFlag, branch)
+	_, errs := command.Run(app, args...)
+	return errs
+}
+
+func CurrentBranch() (string, error) {
+	out, _ := command.Run("git", "branch", "--show-current")
+	if len(out) == 0 {
+		return "", errors.New("Couldn't get current branch - is HEAD detached?")
+	}
+	return out[0], nil
+}
+
+// DefaultBranch returns the branch HEAD points to in the repo at repoPath. In a bare clone
+// that's the remote's default branch.
+func DefaultBranch(repoPath string) (string, error) {
+	out, _ := command.Run("git", inRepo(repoPath, "symbolic-ref", "--short", "HEAD")...)
+	if len(out) == 0 {
+		return "", errors.New("Couldn't get the default branch")
+	}
+	return out[0], nil
+}
+
+// ListBranches returns the local branches of the repo at repoPath.
+func ListBranches(repoPath string) ([]string, error) {
+	out, err := command.Output(repoPath, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
 }
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"os"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/command"
+)
+
+// DiffContents renders a unified diff of path going from old to new, which don't need to be
+// on disk.
+func DiffContents(path string, old, new []byte) (string, error) {
+	oldFile, err := writeTemp(old)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(oldFile)
+	newFile, err := writeTemp(new)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(newFile)
+
+	// Exits 1 when the files differ, so the output is what matters rather than the status
+	out, _ := command.Run("git", "diff", "--no-index", "--no-color", "--", oldFile, newFile)
+	// git drops the leading / of absolute paths in the a/ and b/ names
+	diff := strings.Join(out, "\n")
+	diff = strings.ReplaceAll(diff, strings.TrimPrefix(oldFile, "/"), strings.TrimPrefix(path, "/"))
+	diff = strings.ReplaceAll(diff, strings.TrimPrefix(newFile, "/"), strings.TrimPrefix(path, "/"))
+	return diff, nil
+}
+
+func writeTemp(content []byte) (string, error) {
+	file, err := os.CreateTemp("", "twt-diff-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(content); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
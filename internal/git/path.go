@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/j-clemons/twt/internal/checks"
 	"github.com/j-clemons/twt/internal/command"
 )
 
+// getGitDir returns the git dir the current dir is in, e.g. the bare repo from its common
+// files dir.
 func getGitDir() (string, error) {
-	return os.Getwd()
+	out, _ := command.Run("git", "rev-parse", "--absolute-git-dir")
+	if len(out) == 0 {
+		return os.Getwd()
+	}
+	return out[0], nil
 }
 
 func getBaseFromWorktree() (string, error) {
@@ -39,3 +46,48 @@ func GetBaseDir() (string, error) {
 	}
 	return "", &NotInGitDirError{}
 }
+
+// BaseDirOf returns the base dir of the repo path is in, like GetBaseDir does for the
+// current dir.
+func BaseDirOf(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s isn't a directory", path)
+	}
+
+	out, err := command.Output(absPath, "git", "rev-parse", "--is-inside-work-tree", "--is-inside-git-dir")
+	if err != nil {
+		return "", &NotInGitDirError{}
+	}
+	inside := strings.Fields(string(out))
+	if len(inside) < 2 {
+		return "", &NotInGitDirError{}
+	}
+	switch {
+	case inside[0] == "true":
+		top, err := command.Output(absPath, "git", "rev-parse", "--show-toplevel")
+		if err != nil {
+			return "", err
+		}
+		return filepath.Dir(strings.TrimSpace(string(top))), nil
+	case inside[1] == "true":
+		gitDir, err := command.Output(absPath, "git", "rev-parse", "--absolute-git-dir")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(gitDir)), nil
+	}
+	return "", &NotInGitDirError{}
+}
+
+// GetWorktreeRoot returns the top level dir of the worktree the current dir is in.
+func GetWorktreeRoot() (string, error) {
+	out, _ := command.Run("git", "rev-parse", "--show-toplevel")
+	if len(out) == 0 {
+		return "", errors.New("Couldn't get worktree dir - is this inside a worktree?")
+	}
+	return out[0], nil
+}
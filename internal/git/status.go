@@ -0,0 +1,45 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/command"
+)
+
+func RecentCommits(worktreePath string, count int) ([]string, error) {
+	args := []string{"-C", worktreePath, "log", "--oneline", "--no-decorate", "-n", fmt.Sprint(count)}
+	out, stderr := command.Run("git", args...)
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("git log failed in %s: %v", worktreePath, stderr)
+	}
+	return out, nil
+}
+
+func StatusSummary(worktreePath string) ([]string, error) {
+	args := []string{"-C", worktreePath, "status", "--short", "--branch"}
+	out, stderr := command.Run("git", args...)
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("git status failed in %s: %v", worktreePath, stderr)
+	}
+	return out, nil
+}
+
+func IsDirty(worktreePath string) bool {
+	out, _ := command.Run("git", "-C", worktreePath, "status", "--porcelain")
+	return len(out) > 0
+}
+
+// UnpushedCount returns how many commits on the worktree's HEAD aren't on its upstream, or
+// on any remote if the branch has no upstream.
+func UnpushedCount(worktreePath string) (int, error) {
+	out, stderr := command.Run("git", "-C", worktreePath, "rev-list", "--count", "@{upstream}..HEAD")
+	if len(stderr) > 0 {
+		out, stderr = command.Run("git", "-C", worktreePath, "rev-list", "--count", "HEAD", "--not", "--remotes")
+	}
+	if len(stderr) > 0 || len(out) == 0 {
+		return 0, fmt.Errorf("couldn't count unpushed commits in %s: %v", worktreePath, stderr)
+	}
+	return strconv.Atoi(strings.TrimSpace(out[0]))
+}
@@ -1,12 +1,21 @@
 package git
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/j-clemons/twt/internal/command"
 )
 
 func RemoveWorktree(name, branch string, force, deleteBranch bool) []string {
+	return RemoveWorktreeFromRepo("", name, branch, force, deleteBranch)
+}
+
+// RemoveWorktreeFromRepo removes a worktree of the repo at repoPath, rather than the repo
+// of the current directory. An empty repoPath uses the current directory.
+func RemoveWorktreeFromRepo(repoPath, worktreePath, branch string, force, deleteBranch bool) []string {
 	app := "git"
-	args := []string{"worktree", "remove", name}
+	args := inRepo(repoPath, "worktree", "remove", worktreePath)
 	if force {
 		args = append(args, "--force")
 	}
@@ -18,7 +27,66 @@ func RemoveWorktree(name, branch string, force, deleteBranch bool) []string {
 	}
 
 	if deleteBranch {
-		DeleteBranch(branch, force)
+		DeleteBranchFromRepo(repoPath, branch, force)
+	}
+	return nil
+}
+
+// AddWorktree checks out branch in a new worktree of the repo at repoPath. If startPoint is
+// given the branch is created there, otherwise it must already exist.
+func AddWorktree(repoPath, worktreePath, branch, startPoint string) error {
+	args := inRepo(repoPath, "worktree", "add", worktreePath, branch)
+	if startPoint != "" {
+		args = inRepo(repoPath, "worktree", "add", "-b", branch, worktreePath, startPoint)
+	}
+	if _, err := command.Output("", "git", args...); err != nil {
+		return fmt.Errorf("couldn't create worktree: %w", err)
+	}
+	return nil
+}
+
+func inRepo(repoPath string, args ...string) []string {
+	if repoPath == "" {
+		return args
+	}
+	return append([]string{"-C", repoPath}, args...)
+}
+
+type Worktree struct {
+	Path     string
+	Branch   string
+	Bare     bool
+	Detached bool
+}
+
+// ListWorktrees parses `git worktree list --porcelain` for the repo at repoPath.
+func ListWorktrees(repoPath string) ([]Worktree, error) {
+	out, stderr := command.Run("git", inRepo(repoPath, "worktree", "list", "--porcelain")...)
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("git worktree list failed: %v", stderr)
+	}
+
+	var worktrees []Worktree
+	for _, line := range out {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			worktrees = append(worktrees, Worktree{Path: value})
+		case "branch":
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(value, "refs/heads/")
+		case "bare":
+			worktrees[len(worktrees)-1].Bare = true
+		case "detached":
+			worktrees[len(worktrees)-1].Detached = true
+		}
+	}
+	return worktrees, nil
+}
+
+// PruneWorktrees drops the repo's records of worktrees whose dirs were deleted.
+func PruneWorktrees(repoPath string) error {
+	if _, err := command.Output("", "git", inRepo(repoPath, "worktree", "prune")...); err != nil {
+		return fmt.Errorf("couldn't prune worktrees: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,201 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Kind is what a step acts on.
+type Kind string
+
+const (
+	Git        Kind = "git"
+	Tmux       Kind = "tmux"
+	Filesystem Kind = "fs"
+	State      Kind = "state"
+	Script     Kind = "script"
+)
+
+// Step is a single operation of a plan. Steps are described up front so a plan can be
+// printed instead of run, e.g. for --dry-run.
+type Step struct {
+	Kind        Kind   `json:"kind"`
+	Description string `json:"description"`
+	// What the step acts on in bulk plans, e.g. a session name. Steps with the same target
+	// are run together by ExecuteEach.
+	Target string `json:"target,omitempty"`
+	// What undoing the step does, if it can be undone
+	Rollback string `json:"rollback,omitempty"`
+	run      func() error
+	undo     func() error
+}
+
+// OnRollback sets how to undo the step if a later step fails.
+func (s *Step) OnRollback(description string, undo func() error) *Step {
+	s.Rollback = description
+	s.undo = undo
+	return s
+}
+
+// Plan is the list of operations a command will carry out, built before anything is changed.
+type Plan struct {
+	Command string  `json:"command"`
+	Steps   []*Step `json:"steps"`
+	// Things worth knowing that aren't operations, e.g. why a session is skipped
+	Notes []string `json:"notes,omitempty"`
+	// Problems steps reported while running that didn't stop the plan
+	warnings []string
+}
+
+func New(command string) *Plan {
+	return &Plan{Command: command, Steps: []*Step{}}
+}
+
+// Add appends a step that calls run when the plan is executed.
+func (p *Plan) Add(kind Kind, description string, run func() error) *Step {
+	return p.AddFor("", kind, description, run)
+}
+
+// AddFor appends a step for target, see Step.Target.
+func (p *Plan) AddFor(target string, kind Kind, description string, run func() error) *Step {
+	step := &Step{Kind: kind, Description: description, Target: target, run: run}
+	p.Steps = append(p.Steps, step)
+	return step
+}
+
+func (p *Plan) Note(format string, args ...any) {
+	p.Notes = append(p.Notes, fmt.Sprintf(format, args...))
+}
+
+// Warn records a problem that doesn't stop the plan, e.g. a script allowed to fail. Steps call
+// it while running.
+func (p *Plan) Warn(format string, args ...any) {
+	p.warnings = append(p.warnings, fmt.Sprintf(format, args...))
+}
+
+var ErrInterrupted = errors.New("interrupted")
+
+// Failure is returned by Execute when a step fails, after the steps before it were undone.
+type Failure struct {
+	Step string
+	Err  error
+	// Rollback descriptions of the steps undone, in the order they were undone
+	RolledBack []string
+	// Rollbacks that failed, leaving something to clean up by hand
+	RollbackErrs []error
+}
+
+func (f *Failure) Error() string {
+	if errors.Is(f.Err, ErrInterrupted) {
+		return fmt.Sprintf("interrupted after: %s", f.Step)
+	}
+	return fmt.Sprintf("%s: %v", f.Step, f.Err)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Warnings is returned by Execute when every step succeeded but some warned.
+type Warnings struct {
+	Messages []string
+}
+
+func (w *Warnings) Error() string {
+	return fmt.Sprintf("done, with %d warning(s)", len(w.Messages))
+}
+
+// Execute runs the steps in order. If one fails, or the process is interrupted (e.g. Ctrl-C),
+// the steps already done are undone in reverse order and a *Failure is returned. If they all
+// succeed but some warned, *Warnings is returned.
+func (p *Plan) Execute() error {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	for i, step := range p.Steps {
+		err := step.run()
+		done := i
+		if err == nil {
+			select {
+			case <-interrupts:
+				err = ErrInterrupted
+				done = i + 1
+			default:
+				continue
+			}
+		}
+
+		failure := &Failure{Step: step.Description, Err: err}
+		failure.rollback(p.Steps[:done])
+		return failure
+	}
+	if len(p.warnings) > 0 {
+		return &Warnings{Messages: p.warnings}
+	}
+	return nil
+}
+
+func (f *Failure) rollback(done []*Step) {
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		if step.undo == nil {
+			continue
+		}
+		if err := step.undo(); err != nil {
+			f.RollbackErrs = append(f.RollbackErrs, fmt.Errorf("%s: %w", step.Rollback, err))
+			continue
+		}
+		f.RolledBack = append(f.RolledBack, step.Rollback)
+	}
+}
+
+// ExecuteEach runs the steps of each target in order. Once a step fails the rest of its
+// target's steps are skipped, but other targets carry on. Returns the error of each target
+// that failed.
+func (p *Plan) ExecuteEach() map[string]error {
+	errs := make(map[string]error)
+	for _, step := range p.Steps {
+		if errs[step.Target] != nil {
+			continue
+		}
+		if err := step.run(); err != nil {
+			errs[step.Target] = fmt.Errorf("%s: %w", step.Description, err)
+		}
+	}
+	return errs
+}
+
+// Print writes the plan for a person to read, or as JSON for scripts.
+func (p *Plan) Print(w io.Writer, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	}
+
+	fmt.Fprintf(w, "Plan for %s:\n", p.Command)
+	if len(p.Steps) == 0 {
+		fmt.Fprintln(w, "  nothing to do")
+	}
+	target := ""
+	for i, step := range p.Steps {
+		if step.Target != "" && step.Target != target {
+			fmt.Fprintf(w, "  %s:\n", step.Target)
+			target = step.Target
+		}
+		fmt.Fprintf(w, "  %2d. %-7s %s\n", i+1, "["+string(step.Kind)+"]", step.Description)
+		if step.Rollback != "" {
+			fmt.Fprintf(w, "      %-7s on failure: %s\n", "", step.Rollback)
+		}
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintf(w, "  note: %s\n", note)
+	}
+	return nil
+}
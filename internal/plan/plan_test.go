@@ -0,0 +1,130 @@
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/plan"
+)
+
+func TestExecuteStopsAtFirstFailure(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) func() error {
+		return func() error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	p := plan.New("test")
+	p.Add(plan.Git, "first", step("first", nil))
+	p.Add(plan.Tmux, "second", step("second", errors.New("boom")))
+	p.Add(plan.State, "third", step("third", nil))
+
+	if err := p.Execute(); err == nil {
+		t.Fatalf("Expected an error from the second step")
+	}
+	if len(ran) != 2 {
+		t.Fatalf("Expected 2 steps to run but got %v", ran)
+	}
+}
+
+func TestExecuteEachIsolatesTargets(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) func() error {
+		return func() error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	p := plan.New("test")
+	p.AddFor("a", plan.Tmux, "a1", step("a1", errors.New("boom")))
+	p.AddFor("a", plan.State, "a2", step("a2", nil))
+	p.AddFor("b", plan.Tmux, "b1", step("b1", nil))
+	p.AddFor("b", plan.State, "b2", step("b2", nil))
+
+	errs := p.ExecuteEach()
+	if errs["a"] == nil || errs["b"] != nil {
+		t.Fatalf("Expected only a to fail but got %v", errs)
+	}
+	if len(ran) != 3 {
+		t.Fatalf("Expected a2 to be skipped but ran %v", ran)
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	p := plan.New("test")
+	p.Add(plan.Filesystem, "create dir", func() error {
+		t.Fatalf("Printing shouldn't run steps")
+		return nil
+	})
+	p.Note("something %s", "skipped")
+
+	var out bytes.Buffer
+	if err := p.Print(&out, true); err != nil {
+		t.Fatalf("Print failed: %s", err)
+	}
+
+	var printed plan.Plan
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("Couldn't parse printed plan: %s", err)
+	}
+	if len(printed.Steps) != 1 || printed.Steps[0].Kind != plan.Filesystem || printed.Notes[0] != "something skipped" {
+		t.Fatalf("Unexpected plan %+v", printed)
+	}
+}
+
+func TestExecuteRollsBackInReverse(t *testing.T) {
+	var undone []string
+	undo := func(name string) func() error {
+		return func() error {
+			undone = append(undone, name)
+			return nil
+		}
+	}
+	ok := func() error { return nil }
+
+	p := plan.New("test")
+	p.Add(plan.Git, "create branch", ok).OnRollback("delete branch", undo("branch"))
+	p.Add(plan.Tmux, "clear", ok)
+	p.Add(plan.Tmux, "create session", ok).OnRollback("kill session", undo("session"))
+	p.Add(plan.Script, "run script", func() error { return errors.New("exit status 1") }).
+		OnRollback("never run", undo("script"))
+
+	err := p.Execute()
+	var failure *plan.Failure
+	if !errors.As(err, &failure) {
+		t.Fatalf("Expected a *plan.Failure but got %v", err)
+	}
+	if len(undone) != 2 || undone[0] != "session" || undone[1] != "branch" {
+		t.Fatalf("Expected session then branch to be undone but got %v", undone)
+	}
+	if len(failure.RolledBack) != 2 || failure.RolledBack[0] != "kill session" {
+		t.Fatalf("Unexpected rollback report %v", failure.RolledBack)
+	}
+}
+
+func TestExecuteReportsWarnings(t *testing.T) {
+	p := plan.New("test")
+	p.Add(plan.Script, "run script", func() error {
+		p.Warn("script failed")
+		return nil
+	})
+	ran := false
+	p.Add(plan.Tmux, "switch", func() error {
+		ran = true
+		return nil
+	})
+
+	err := p.Execute()
+	var warnings *plan.Warnings
+	if !errors.As(err, &warnings) {
+		t.Fatalf("Expected *plan.Warnings but got %v", err)
+	}
+	if !ran || len(warnings.Messages) != 1 || warnings.Messages[0] != "script failed" {
+		t.Fatalf("Expected the plan to carry on with 1 warning but got %v (ran: %t)", warnings.Messages, ran)
+	}
+}
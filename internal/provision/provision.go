@@ -0,0 +1,80 @@
+package provision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the manifest of per-worktree resources in a repo's common files dir.
+const FileName = "provision.json"
+
+// Resource is something each worktree gets, set up by go and torn down by rm, e.g. a compose
+// stack or a database.
+type Resource struct {
+	Name string `json:"name"`
+	// Shell command setting it up, run in the new worktree
+	Setup string `json:"setup"`
+	// Shell command undoing Setup, run in the worktree when it's removed. Optional.
+	Teardown string `json:"teardown,omitempty"`
+	// Whether Teardown brings the worktree's compose project down, so rm doesn't itself
+	ComposeDown bool `json:"compose_down,omitempty"`
+}
+
+type Manifest struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Path returns where the manifest of the common files dir is.
+func Path(commonDir string) string {
+	return filepath.Join(commonDir, FileName)
+}
+
+// Load reads the manifest in the common files dir. A missing manifest is an empty one.
+func Load(commonDir string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(Path(commonDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	return Parse(data)
+}
+
+// Parse reads a manifest from its contents, e.g. the bytes the user approved.
+func Parse(data []byte) (Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, resource := range manifest.Resources {
+		if resource.Name == "" {
+			return manifest, fmt.Errorf("%s: resource %d has no name", FileName, i+1)
+		}
+		if resource.Setup == "" {
+			return manifest, fmt.Errorf("%s: resource %s has no setup command", FileName, resource.Name)
+		}
+		if seen[resource.Name] {
+			return manifest, fmt.Errorf("%s: resource %s is declared twice", FileName, resource.Name)
+		}
+		seen[resource.Name] = true
+	}
+	return manifest, nil
+}
+
+// Find returns the resource with the given name.
+func (m Manifest) Find(name string) (Resource, bool) {
+	for _, resource := range m.Resources {
+		if resource.Name == name {
+			return resource, true
+		}
+	}
+	return Resource{}, false
+}
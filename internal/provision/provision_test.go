@@ -0,0 +1,67 @@
+package provision_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/provision"
+)
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name              string
+		manifest          string
+		expectedResources int
+		expectedSuccess   bool
+	}{
+		{name: "No manifest", expectedResources: 0, expectedSuccess: true},
+		{
+			name:              "Setup and teardown",
+			manifest:          `{"resources":[{"name":"db","setup":"createdb x","teardown":"dropdb x"},{"name":"deps","setup":"npm ci"}]}`,
+			expectedResources: 2,
+			expectedSuccess:   true,
+		},
+		{name: "Missing setup", manifest: `{"resources":[{"name":"db"}]}`, expectedSuccess: false},
+		{name: "Missing name", manifest: `{"resources":[{"setup":"true"}]}`, expectedSuccess: false},
+		{name: "Duplicate name", manifest: `{"resources":[{"name":"a","setup":"true"},{"name":"a","setup":"true"}]}`, expectedSuccess: false},
+		{name: "Invalid JSON", manifest: `{"resources":`, expectedSuccess: false},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		if c.manifest != "" {
+			if err := os.WriteFile(provision.Path(dir), []byte(c.manifest), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		manifest, err := provision.Load(dir)
+		if c.expectedSuccess && err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if !c.expectedSuccess && err == nil {
+			t.Fatalf("%s: Expected error but got success", c.name)
+		}
+		if c.expectedSuccess && len(manifest.Resources) != c.expectedResources {
+			t.Fatalf("%s: Expected %d resources but got %d", c.name, c.expectedResources, len(manifest.Resources))
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	manifest, err := provision.Parse([]byte(`{"resources":[{"name":"db","setup":"createdb x","teardown":"dropdb x","compose_down":true}]}`))
+	if err != nil {
+		t.Fatalf("Parse: Expected success but got error: %s", err)
+	}
+	resource, ok := manifest.Find("db")
+	if !ok || resource.Setup != "createdb x" || resource.Teardown != "dropdb x" || !resource.ComposeDown {
+		t.Fatalf("Find db: Expected the declared resource but got %+v, %t", resource, ok)
+	}
+	if _, ok := manifest.Find("cache"); ok {
+		t.Fatalf("Find cache: Expected no resource but found one")
+	}
+
+	if _, err := provision.Parse([]byte(`{"resources":[{"name":"db"}]}`)); err == nil {
+		t.Fatalf("Parse without setup: Expected error but got success")
+	}
+}
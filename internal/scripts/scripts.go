@@ -0,0 +1,404 @@
+package scripts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// How much of a script is read to find its shebang and whether it's binary
+const binaryCheckSize = 8000
+
+// Script is a hook script in a repo's scripts dir, run for a phase of a command, e.g. the
+// post phase of go.
+type Script struct {
+	// Path relative to the scripts dir, e.g. "go/post.d/10-env.sh", used to enable or
+	// disable it in the config
+	Name    string
+	Path    string
+	Command string
+	Phase   string
+	// The interpreter and its args from the script's shebang line, if it has one
+	Interpreter []string
+	Executable  bool
+	// Whether it's a compiled program rather than a text script
+	Binary bool
+}
+
+// Discover returns the scripts for a phase of command in scriptsDir in the order they run:
+// <command>/<phase>.sh, then the files in <command>/<phase>.d/ in lexical order. Hidden
+// files and editor backups (ending in ~) in the dir are ignored.
+func Discover(scriptsDir, command, phase string) ([]Script, error) {
+	var scripts []Script
+
+	single := filepath.Join(scriptsDir, command, phase+".sh")
+	if info, err := os.Stat(single); err == nil && info.Mode().IsRegular() {
+		script, err := load(scriptsDir, single, command, phase, info)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(scriptsDir, command, phase+".d"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return scripts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// ReadDir already sorts by name
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		path := filepath.Join(scriptsDir, command, phase+".d", name)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		script, err := load(scriptsDir, path, command, phase, info)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// DiscoverAll returns the scripts for every command and phase in scriptsDir, by command
+// then phase.
+func DiscoverAll(scriptsDir string) ([]Script, error) {
+	commands, err := os.ReadDir(scriptsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []Script
+	for _, command := range commands {
+		if !command.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(scriptsDir, command.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		phases := make(map[string]bool)
+		for _, entry := range entries {
+			if phase, ok := strings.CutSuffix(entry.Name(), ".d"); ok && entry.IsDir() {
+				phases[phase] = true
+			} else if phase, ok := strings.CutSuffix(entry.Name(), ".sh"); ok && !entry.IsDir() {
+				phases[phase] = true
+			}
+		}
+		names := make([]string, 0, len(phases))
+		for phase := range phases {
+			names = append(names, phase)
+		}
+		sort.Strings(names)
+
+		for _, phase := range names {
+			found, err := Discover(scriptsDir, command.Name(), phase)
+			if err != nil {
+				return nil, err
+			}
+			scripts = append(scripts, found...)
+		}
+	}
+	return scripts, nil
+}
+
+func load(scriptsDir, path, command, phase string, info fs.FileInfo) (Script, error) {
+	name, err := filepath.Rel(scriptsDir, path)
+	if err != nil {
+		return Script{}, err
+	}
+	interpreter, binary, err := readHead(path)
+	if err != nil {
+		return Script{}, err
+	}
+	return Script{
+		Name:        filepath.ToSlash(name),
+		Path:        path,
+		Command:     command,
+		Phase:       phase,
+		Interpreter: interpreter,
+		Executable:  info.Mode().Perm()&0111 != 0,
+		Binary:      binary,
+	}, nil
+}
+
+// readHead returns the interpreter and args of the #! line at the top of the file at path,
+// or nil if it hasn't got one, and whether the file is binary, going by a NUL byte near the
+// start like git does.
+func readHead(path string) ([]string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, binaryCheckSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, false, err
+	}
+	interpreter, binary := parseHead(head[:n])
+	return interpreter, binary, nil
+}
+
+// parseHead is readHead for the start of a script's content.
+func parseHead(head []byte) ([]string, bool) {
+	head = head[:min(len(head), binaryCheckSize)]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, true
+	}
+
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	shebang, ok := strings.CutPrefix(strings.TrimRight(string(line), "\r"), "#!")
+	if !ok {
+		return nil, false
+	}
+	return strings.Fields(shebang), false
+}
+
+// Enabled reports whether the script runs, given the config's enabled setting: script names
+// or glob patterns mapped to whether they run. Scripts run unless the most specific pattern
+// matching them says otherwise.
+func (s Script) Enabled(enabled map[string]bool) bool {
+	patterns := make([]string, 0, len(enabled))
+	for pattern := range enabled {
+		patterns = append(patterns, pattern)
+	}
+	if pattern, ok := s.Match(patterns); ok {
+		return enabled[pattern]
+	}
+	return true
+}
+
+// Match returns the most specific of the patterns matching the script's name: the name
+// itself, then the longest glob pattern, so "go/post.d/*" beats "go/*".
+func (s Script) Match(patterns []string) (string, bool) {
+	if slices.Contains(patterns, s.Name) {
+		return s.Name, true
+	}
+	sorted := slices.Clone(patterns)
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	for _, pattern := range sorted {
+		if matched, _ := filepath.Match(pattern, s.Name); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Problem returns why the script can't run, or "" if it can: a binary needs to be
+// executable, and a shebang has to name an interpreter that's installed. Text scripts
+// without one are run by sh.
+func (s Script) Problem() string {
+	if s.Binary {
+		if s.Executable {
+			return ""
+		}
+		return "binary isn't executable"
+	}
+	if len(s.Interpreter) == 0 {
+		return ""
+	}
+	if _, err := exec.LookPath(s.program()); err != nil {
+		return fmt.Sprintf("interpreter %s not found", s.program())
+	}
+	return ""
+}
+
+// program returns the interpreter the shebang runs, looking through #!/usr/bin/env [-S].
+func (s Script) program() string {
+	if len(s.Interpreter) == 0 {
+		return "sh"
+	}
+	interpreter := s.Interpreter[0]
+	if filepath.Base(interpreter) == "env" {
+		for _, arg := range s.Interpreter[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				return arg
+			}
+		}
+	}
+	return interpreter
+}
+
+// Shells a text script can be sourced by, keeping its path as $0
+var shells = []string{"sh", "bash", "dash", "zsh", "ksh", "mksh", "ash"}
+
+// contentFD is where a text script's content is handed to its interpreter.
+const contentFD = "/dev/fd/3"
+
+// Cmd returns the command running a text script's content from contentFD: its shebang
+// interpreter, or sh, with the content as the script. Shells source it with the script's
+// path as $0, so it can find the files next to it.
+func (s Script) Cmd() *exec.Cmd {
+	interpreter := []string{"sh"}
+	if len(s.Interpreter) > 0 {
+		interpreter = s.Interpreter
+	}
+	args := slices.Clone(interpreter[1:])
+	if slices.Contains(shells, filepath.Base(s.program())) {
+		args = append(args, "-c", ". "+contentFD, s.Path)
+	} else {
+		args = append(args, contentFD)
+	}
+	return exec.Command(interpreter[0], args...)
+}
+
+// RunOptions are how a script is run.
+type RunOptions struct {
+	Dir string
+	// How long it may run before it and everything it started are killed, 0 for no limit
+	Timeout time.Duration
+	// Start from a clean environment rather than twt's
+	CleanEnv bool
+	// Vars set on top of the environment, as KEY=value
+	Env []string
+}
+
+// Vars kept in a clean environment
+var cleanEnvVars = []string{"PATH", "HOME", "USER", "SHELL", "TERM", "LANG", "TMPDIR"}
+
+// How long a script gets to exit after SIGTERM before it's killed
+const killGrace = 5 * time.Second
+
+// Run runs content, the script as it was approved, with its output shown, and returns an
+// error if it exits non-zero or runs out of time. A text script's content is handed to its
+// interpreter through a pipe, and a binary runs from a private copy, so what runs is exactly
+// what was approved even if the file changes meanwhile. It runs in a process group of its
+// own, so when it's stopped, by timing out or Ctrl-C, everything it started is stopped too.
+// It gets no input.
+func (s Script) Run(content []byte, opts RunOptions) error {
+	approved := s
+	approved.Interpreter, approved.Binary = parseHead(content)
+	if problem := approved.Problem(); problem != "" {
+		return fmt.Errorf("can't run %s: %s", s.Name, problem)
+	}
+	if approved.Binary {
+		return approved.runCopy(content, opts)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("couldn't pass %s to its interpreter: %w", s.Name, err)
+	}
+	// Closing the reader once it's done stops the write if the script didn't read it all
+	defer reader.Close()
+	go func() {
+		writer.Write(content)
+		writer.Close()
+	}()
+
+	cmd := approved.Cmd()
+	cmd.ExtraFiles = []*os.File{reader}
+	return run(cmd, s.Name, opts)
+}
+
+// runCopy runs a binary from a private copy of content, named as the script.
+func (s Script) runCopy(content []byte, opts RunOptions) error {
+	dir, err := os.MkdirTemp("", "twt-script-")
+	if err != nil {
+		return fmt.Errorf("couldn't copy %s to run it: %w", s.Name, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(s.Path))
+	if err := os.WriteFile(path, content, 0700); err != nil {
+		return fmt.Errorf("couldn't copy %s to run it: %w", s.Name, err)
+	}
+	cmd := exec.Command(path)
+	cmd.Args[0] = s.Path
+	return run(cmd, s.Name, opts)
+}
+
+// RunCommand runs a shell command like a script, see Script.Run. name is what it's called
+// in errors.
+func RunCommand(name, command string, opts RunOptions) error {
+	return run(exec.Command("sh", "-c", command), name, opts)
+}
+
+func run(cmd *exec.Cmd, name string, opts RunOptions) error {
+	cmd.Dir = opts.Dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Env = os.Environ()
+	if opts.CleanEnv {
+		cmd.Env = nil
+		for _, envVar := range cleanEnvVars {
+			if value, ok := os.LookupEnv(envVar); ok {
+				cmd.Env = append(cmd.Env, envVar+"="+value)
+			}
+		}
+	}
+	cmd.Env = append(cmd.Env, opts.Env...)
+
+	// Ctrl-C only reaches twt's process group, so it's passed on
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("couldn't start %s: %w", name, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", name, err)
+		}
+		return nil
+	case <-timeout:
+		stopGroup(cmd.Process.Pid, done)
+		return fmt.Errorf("%s timed out after %s", name, opts.Timeout)
+	case <-interrupts:
+		stopGroup(cmd.Process.Pid, done)
+		return fmt.Errorf("%s was interrupted", name)
+	}
+}
+
+// stopGroup asks the process group led by pid to stop, killing it if it's still running
+// after killGrace.
+func stopGroup(pid int, done <-chan error) {
+	syscall.Kill(-pid, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		syscall.Kill(-pid, syscall.SIGKILL)
+		<-done
+	}
+}
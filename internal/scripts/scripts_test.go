@@ -0,0 +1,200 @@
+package scripts_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j-clemons/twt/internal/scripts"
+)
+
+func TestDiscover(t *testing.T) {
+	scriptsDir := t.TempDir()
+	files := map[string]string{
+		"go/post.sh":             "#!/bin/sh\necho single\n",
+		"go/post.d/20-npm.sh":    "#!/usr/bin/env sh\necho npm\n",
+		"go/post.d/10-env.py":    "#!/usr/bin/env python3 -u\nprint('env')\n",
+		"go/post.d/30-plain":     "echo plain\n",
+		"go/post.d/.hidden.sh":   "#!/bin/sh\n",
+		"go/post.d/20-npm.sh~":   "#!/bin/sh\n",
+		"go/pre.d/10-check.sh":   "#!/bin/sh\n",
+		"rm/pre.sh":              "#!/bin/sh\n",
+		"go/post.d/nested/x.sh":  "#!/bin/sh\n",
+		"go/post.d/40-broken.sh": "#!/no/such/interpreter\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(scriptsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := scripts.Discover(scriptsDir, "go", "post")
+	if err != nil {
+		t.Fatalf("Discover: Expected success but got error: %s", err)
+	}
+	var names []string
+	for _, script := range found {
+		names = append(names, script.Name)
+	}
+	expected := []string{"go/post.sh", "go/post.d/10-env.py", "go/post.d/20-npm.sh", "go/post.d/30-plain", "go/post.d/40-broken.sh"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("Discover: Expected %v but got %v", expected, names)
+	}
+
+	if interpreter := strings.Join(found[1].Interpreter, " "); interpreter != "/usr/bin/env python3 -u" {
+		t.Fatalf("Shebang: Expected /usr/bin/env python3 -u but got %q", interpreter)
+	}
+	if found[3].Interpreter != nil || found[3].Problem() != "" {
+		t.Fatalf("No shebang: Expected no interpreter and no problem but got %v, %q", found[3].Interpreter, found[3].Problem())
+	}
+	if found[4].Problem() == "" {
+		t.Fatalf("Missing interpreter: Expected a problem")
+	}
+	if found[0].Executable {
+		t.Fatalf("Executable: Expected a 0644 script not to be executable")
+	}
+
+	all, err := scripts.DiscoverAll(scriptsDir)
+	if err != nil {
+		t.Fatalf("DiscoverAll: Expected success but got error: %s", err)
+	}
+	if len(all) != len(found)+2 || all[len(found)].Name != "go/pre.d/10-check.sh" || all[len(all)-1].Name != "rm/pre.sh" {
+		t.Fatalf("DiscoverAll: Expected go post, go pre then rm pre scripts but got %d scripts", len(all))
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	script := scripts.Script{Name: "go/post.d/20-npm.sh"}
+	cases := []struct {
+		name     string
+		enabled  map[string]bool
+		expected bool
+	}{
+		{name: "No config", enabled: nil, expected: true},
+		{name: "Disabled by name", enabled: map[string]bool{"go/post.d/20-npm.sh": false}, expected: false},
+		{name: "Disabled by pattern", enabled: map[string]bool{"go/post.d/*": false}, expected: false},
+		{name: "Name beats pattern", enabled: map[string]bool{"go/post.d/*": false, "go/post.d/20-npm.sh": true}, expected: true},
+		{name: "Longer pattern wins", enabled: map[string]bool{"go/*/*": false, "go/post.d/2*": true}, expected: true},
+		{name: "Other script", enabled: map[string]bool{"go/post.d/10-env.sh": false}, expected: true},
+	}
+
+	for _, c := range cases {
+		if enabled := script.Enabled(c.enabled); enabled != c.expected {
+			t.Fatalf("%s: Expected enabled %t but got %t", c.name, c.expected, enabled)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TWT_TEST_INHERITED", "yes")
+	write := func(name, content string) scripts.Script {
+		path := filepath.Join(dir, "go", "post.d", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		found, err := scripts.Discover(dir, "go", "post")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, script := range found {
+			if script.Path == path {
+				return script
+			}
+		}
+		t.Fatalf("%s: Expected to discover it", name)
+		return scripts.Script{}
+	}
+
+	content := func(script scripts.Script) []byte {
+		data, err := os.ReadFile(script.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	env := write("10-env", "#!/bin/sh\necho \"$PWD:$TWT_BRANCH:$TWT_TEST_INHERITED\" > out\n")
+	for _, c := range []struct {
+		name     string
+		clean    bool
+		expected string
+	}{
+		{name: "Inherited env", clean: false, expected: dir + ":feature:yes"},
+		{name: "Clean env", clean: true, expected: dir + ":feature:"},
+	} {
+		err := env.Run(content(env), scripts.RunOptions{Dir: dir, CleanEnv: c.clean, Env: []string{"TWT_BRANCH=feature"}})
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		out, _ := os.ReadFile(filepath.Join(dir, "out"))
+		if got := strings.TrimSpace(string(out)); got != c.expected {
+			t.Fatalf("%s: Expected %q but got %q", c.name, c.expected, got)
+		}
+	}
+
+	// What runs is the content given, even once the file has changed
+	approved := write("15-approved", "#!/bin/sh\necho approved > out\n")
+	approvedContent := content(approved)
+	write("15-approved", "#!/bin/sh\necho swapped > out\n")
+	if err := approved.Run(approvedContent, scripts.RunOptions{Dir: dir}); err != nil {
+		t.Fatalf("Approved content: Expected success but got error: %s", err)
+	}
+	if out, _ := os.ReadFile(filepath.Join(dir, "out")); strings.TrimSpace(string(out)) != "approved" {
+		t.Fatalf("Approved content: Expected the approved content to run but got %q", out)
+	}
+
+	// $0 is still the script's path, so files next to it can be used
+	if err := os.WriteFile(filepath.Join(dir, "go", "lib.sh"), []byte("greeting=hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sibling := write("16-sibling", "#!/bin/sh\n. \"$(dirname \"$0\")/../lib.sh\"\necho \"$0:$greeting\" > out\n")
+	if err := sibling.Run(content(sibling), scripts.RunOptions{Dir: dir}); err != nil {
+		t.Fatalf("Sibling file: Expected success but got error: %s", err)
+	}
+	if out, _ := os.ReadFile(filepath.Join(dir, "out")); strings.TrimSpace(string(out)) != sibling.Path+":hi" {
+		t.Fatalf("Sibling file: Expected %s:hi but got %q", sibling.Path, out)
+	}
+
+	// Other interpreters read the content as their script
+	awk := write("17-awk", "#!/usr/bin/env awk -f\nBEGIN { print \"awk\" > \"out\" }\n")
+	if err := awk.Run(content(awk), scripts.RunOptions{Dir: dir}); err != nil {
+		t.Fatalf("Interpreter: Expected success but got error: %s", err)
+	}
+	if out, _ := os.ReadFile(filepath.Join(dir, "out")); strings.TrimSpace(string(out)) != "awk" {
+		t.Fatalf("Interpreter: Expected awk but got %q", out)
+	}
+
+	// The background sleep is in the script's process group, so it's killed with it
+	slow := write("20-slow", "#!/bin/sh\nsleep 30 &\necho $! > pid\nwait\n")
+	start := time.Now()
+	err := slow.Run(content(slow), scripts.RunOptions{Dir: dir, Timeout: 200 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Timeout: Expected a timeout error but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Timeout: Expected the script to be stopped quickly but took %s", elapsed)
+	}
+	pid, _ := os.ReadFile(filepath.Join(dir, "pid"))
+	var sleepPid int
+	if _, err := fmt.Sscan(string(pid), &sleepPid); err != nil {
+		t.Fatalf("Timeout: Couldn't read the background pid: %s", err)
+	}
+	// Once killed it's gone, or a zombie until it's reaped
+	time.Sleep(100 * time.Millisecond)
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", sleepPid))
+	if err == nil && !strings.Contains(string(stat), ") Z ") {
+		t.Fatalf("Timeout: Expected the background sleep %d to be killed but got %s", sleepPid, stat)
+	}
+}
@@ -1,29 +1,23 @@
 package state
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/fsutil"
 	"github.com/j-clemons/twt/internal/tmux"
 )
 
-const (
-	StateFileName = "sessions.json"
-	ConfigDirName = "twt"
-)
+const StateFileName = "sessions.json"
 
 func getStateFilePath() (string, error) {
-	configDir, err := os.UserConfigDir()
+	twtConfigDir, err := config.Dir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user config directory: %w", err)
-	}
-
-	twtConfigDir := filepath.Join(configDir, ConfigDirName)
-	if err := os.MkdirAll(twtConfigDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create config directory: %w", err)
+		return "", err
 	}
 
 	return filepath.Join(twtConfigDir, StateFileName), nil
@@ -35,30 +29,24 @@ func LoadState() (*State, error) {
 		return nil, err
 	}
 
-	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
-		return NewState(), nil
+	var state *State
+	err = fsutil.WithLock(stateFile, 0644, syscall.LOCK_SH, func() error {
+		state, err = readState(stateFile)
+		return err
+	})
+	if isCorrupt(err) {
+		err = fsutil.WithLock(stateFile, 0644, syscall.LOCK_EX, func() error {
+			state, err = recoverState(stateFile)
+			return err
+		})
 	}
-
-	data, err := os.ReadFile(stateFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read state file: %w", err)
-	}
-
-	var state State
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		return nil, err
 	}
 
-	for name, session := range state.Sessions {
-		if tmux.HasSession(name) {
-			session.Status = StatusActive
-		} else {
-			session.Status = StatusInactive
-		}
-		state.Sessions[name] = session
-	}
+	refreshStatus(state.Sessions)
 
-	return &state, nil
+	return state, nil
 }
 
 func SaveState(state *State) error {
@@ -67,27 +55,48 @@ func SaveState(state *State) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(state, "", "  ")
+	return fsutil.WithLock(stateFile, 0644, syscall.LOCK_EX, func() error {
+		return writeState(stateFile, state)
+	})
+}
+
+// Returned by an Update func to skip writing when nothing changed.
+var errUnchanged = errors.New("state unchanged")
+
+// Update runs a read-modify-write transaction on the state file. The file is locked for the
+// whole transaction so concurrent twt processes can't lose each other's changes. Nothing is
+// written if fn returns an error.
+func Update(fn func(state *State) error) error {
+	stateFile, err := getStateFilePath()
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return err
 	}
 
-	if err := os.WriteFile(stateFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
-	}
+	return fsutil.WithLock(stateFile, 0644, syscall.LOCK_EX, func() error {
+		state, err := readState(stateFile)
+		if isCorrupt(err) {
+			state, err = recoverState(stateFile)
+		}
+		if err != nil {
+			return err
+		}
 
-	return nil
+		err = fn(state)
+		if err == errUnchanged {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return writeState(stateFile, state)
+	})
 }
 
 func RegisterSession(sessionName, repoPath, repoName, branch, worktreePath string) error {
-	state, err := LoadState()
-	if err != nil {
-		return err
-	}
-
 	now := time.Now()
-	session := SessionInfo{
+	return registerSession(SessionInfo{
 		Name:         sessionName,
+		Kind:         KindWorktree,
 		RepoPath:     repoPath,
 		RepoName:     repoName,
 		Branch:       branch,
@@ -95,38 +104,131 @@ func RegisterSession(sessionName, repoPath, repoName, branch, worktreePath strin
 		CreatedAt:    now,
 		LastAccessed: now,
 		Status:       StatusActive,
-	}
-
-	state.Sessions[sessionName] = session
+	})
+}
 
-	tmux.SetEnvironment(sessionName, "TWT_REPO_PATH", repoPath)
-	tmux.SetEnvironment(sessionName, "TWT_BRANCH", branch)
-	tmux.SetEnvironment(sessionName, "TWT_MANAGED", "true")
+// RegisterWorkspaceSession registers a session spanning the worktrees of branch in each repo
+// of the workspace, keyed by repo path.
+func RegisterWorkspaceSession(sessionName string, workspace Workspace, branch string, worktrees map[string]string) error {
+	if len(workspace.Repos) == 0 {
+		return fmt.Errorf("workspace %s has no repos", workspace.Name)
+	}
+	first := workspace.Repos[0]
+	now := time.Now()
+	return registerSession(SessionInfo{
+		Name:         sessionName,
+		Kind:         KindWorktree,
+		RepoPath:     first,
+		RepoName:     workspace.Name,
+		Branch:       branch,
+		WorktreePath: worktrees[first],
+		CreatedAt:    now,
+		LastAccessed: now,
+		Status:       StatusActive,
+		Workspace:    workspace.Name,
+		Worktrees:    worktrees,
+	})
+}
 
-	return SaveState(state)
+// RegisterCommonSession registers the session of the repo at repoPath that works in its
+// common files dir.
+func RegisterCommonSession(sessionName, repoPath, commonDir string) error {
+	now := time.Now()
+	return registerSession(SessionInfo{
+		Name:         sessionName,
+		Kind:         KindCommon,
+		RepoPath:     repoPath,
+		RepoName:     filepath.Base(repoPath),
+		WorktreePath: commonDir,
+		CreatedAt:    now,
+		LastAccessed: now,
+		Status:       StatusActive,
+	})
 }
 
-func UnregisterSession(sessionName string) error {
-	state, err := LoadState()
+func registerSession(session SessionInfo) error {
+	err := Update(func(state *State) error {
+		// Re-registering a session, e.g. after it was restored, keeps its history and layout
+		if existing, ok := state.Sessions[session.Name]; ok && existing.WorktreePath == session.WorktreePath {
+			session.CreatedAt = existing.CreatedAt
+			session.Layout = existing.Layout
+			session.Resources = existing.Resources
+		}
+		state.Sessions[session.Name] = session
+		state.registerRepo(session.RepoPath)
+		for repoPath := range session.Worktrees {
+			state.registerRepo(repoPath)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	delete(state.Sessions, sessionName)
-	return SaveState(state)
+	SetSessionEnvironment(session)
+
+	return nil
+}
+
+// SetSessionEnvironment marks a running tmux session as managed by twt.
+func SetSessionEnvironment(session SessionInfo) {
+	tmux.SetEnvironment(session.Name, "TWT_REPO_PATH", session.RepoPath)
+	tmux.SetEnvironment(session.Name, "TWT_BRANCH", session.Branch)
+	tmux.SetEnvironment(session.Name, "TWT_MANAGED", "true")
+}
+
+func UnregisterSession(sessionName string) error {
+	return Update(func(state *State) error {
+		delete(state.Sessions, sessionName)
+		return nil
+	})
 }
 
 func UpdateLastAccessed(sessionName string) error {
+	return Update(func(state *State) error {
+		session, exists := state.Sessions[sessionName]
+		if !exists {
+			// Not a twt session, don't rewrite the file for nothing
+			return errUnchanged
+		}
+		session.LastAccessed = time.Now()
+		state.Sessions[sessionName] = session
+		return nil
+	})
+}
+
+func SaveLayout(sessionName string, layout *tmux.Layout) error {
+	return Update(func(state *State) error {
+		session, exists := state.Sessions[sessionName]
+		if !exists {
+			return fmt.Errorf("session %s isn't managed by twt", sessionName)
+		}
+		session.Layout = layout
+		state.Sessions[sessionName] = session
+		return nil
+	})
+}
+
+func GetSession(sessionName string) (SessionInfo, bool, error) {
 	state, err := LoadState()
 	if err != nil {
-		return err
+		return SessionInfo{}, false, err
 	}
+	session, exists := state.Sessions[sessionName]
+	return session, exists, nil
+}
 
-	if session, exists := state.Sessions[sessionName]; exists {
-		session.LastAccessed = time.Now()
+func SetHibernated(sessionName string, hibernated bool) error {
+	return Update(func(state *State) error {
+		session, exists := state.Sessions[sessionName]
+		if !exists {
+			return fmt.Errorf("session %s isn't managed by twt", sessionName)
+		}
+		if session.Hibernated == hibernated {
+			return errUnchanged
+		}
+		session.Hibernated = hibernated
 		state.Sessions[sessionName] = session
-		return SaveState(state)
-	}
-
-	return nil
+		return nil
+	})
 }
@@ -0,0 +1,217 @@
+package state_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+func useTempConfigDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("Couldn't create config dir: %s", err)
+	}
+	return filepath.Join(configDir, state.StateFileName)
+}
+
+func addSession(name string) func(*state.State) error {
+	return func(s *state.State) error {
+		s.Sessions[name] = state.SessionInfo{Name: name}
+		return nil
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	useTempConfigDir(t)
+
+	count := 20
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := state.Update(addSession(fmt.Sprintf("session_%d", i))); err != nil {
+				t.Errorf("Update failed: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s, err := state.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %s", err)
+	}
+	if len(s.Sessions) != count {
+		t.Fatalf("Expected %d sessions but got %d", count, len(s.Sessions))
+	}
+}
+
+func TestRecoverFromCorruptState(t *testing.T) {
+	stateFile := useTempConfigDir(t)
+
+	// Two writes so the first is kept as the backup
+	if err := state.Update(addSession("first")); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if err := state.Update(addSession("second")); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if err := os.WriteFile(stateFile, []byte(`{"version": "1.0", "sess`), 0644); err != nil {
+		t.Fatalf("Couldn't corrupt state file: %s", err)
+	}
+
+	s, err := state.LoadState()
+	if err != nil {
+		t.Fatalf("Expected recovery but got error: %s", err)
+	}
+	if _, ok := s.Sessions["first"]; !ok || len(s.Sessions) != 1 {
+		t.Fatalf("Expected the backup state with one session but got %v", s.Sessions)
+	}
+
+	corrupt, _ := filepath.Glob(stateFile + ".corrupt-*")
+	if len(corrupt) != 1 {
+		t.Fatalf("Expected the corrupt file to be kept but found %v", corrupt)
+	}
+}
+
+func TestRecoverWithoutBackup(t *testing.T) {
+	stateFile := useTempConfigDir(t)
+
+	if err := os.WriteFile(stateFile, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Couldn't write state file: %s", err)
+	}
+
+	if err := state.Update(addSession("new")); err != nil {
+		t.Fatalf("Expected recovery but got error: %s", err)
+	}
+
+	s, err := state.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %s", err)
+	}
+	if _, ok := s.Sessions["new"]; !ok || len(s.Sessions) != 1 {
+		t.Fatalf("Expected a fresh state with one session but got %v", s.Sessions)
+	}
+}
+
+func TestRepoAliases(t *testing.T) {
+	useTempConfigDir(t)
+
+	if err := state.RegisterRepo("/code/api.git"); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if err := state.RegisterRepo("/other/api"); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+
+	repo, ok, err := state.LookupRepo("api-2")
+	if err != nil || !ok || repo.Path != "/other/api" {
+		t.Fatalf("Expected api-2 to be /other/api but got %+v, %t, %v", repo, ok, err)
+	}
+
+	if _, err := state.AddRepo("/other/api", "api"); err == nil {
+		t.Fatalf("Expected an error reusing the api alias")
+	}
+	if _, err := state.AddRepo("/other/api", "web"); err != nil {
+		t.Fatalf("Expected success renaming the alias but got error: %s", err)
+	}
+
+	repos, err := state.ListRepos()
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if len(repos) != 2 || repos[0].Alias != "api" || repos[1].Alias != "web" {
+		t.Fatalf("Expected repos api and web but got %+v", repos)
+	}
+}
+
+func TestWorkspaceRepos(t *testing.T) {
+	useTempConfigDir(t)
+
+	if _, err := state.SaveWorkspace("feature", []string{"/code/api.git", "/code/web.git"}); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if _, ok, _ := state.LookupRepo("web"); !ok {
+		t.Fatalf("Expected workspace repos to be registered")
+	}
+	if _, err := state.RemoveRepo("web"); err == nil {
+		t.Fatalf("Expected an error removing a repo used by a workspace")
+	}
+
+	if err := state.RemoveWorkspace("feature"); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if _, err := state.RemoveRepo("web"); err != nil {
+		t.Fatalf("Expected success once the workspace is gone but got error: %s", err)
+	}
+}
+
+func TestWorkspaceNames(t *testing.T) {
+	useTempConfigDir(t)
+
+	cases := []struct {
+		name            string
+		workspace       string
+		expectedSuccess bool
+	}{
+		{name: "Plain name", workspace: "shop", expectedSuccess: true},
+		{name: "Slash", workspace: "shop/v2", expectedSuccess: false},
+		{name: "Empty", workspace: "", expectedSuccess: false},
+		{name: "Repo alias", workspace: "web", expectedSuccess: false},
+	}
+
+	for _, c := range cases {
+		_, err := state.SaveWorkspace(c.workspace, []string{"/code/api.git", "/code/web.git"})
+		if c.expectedSuccess && err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if !c.expectedSuccess && err == nil {
+			t.Fatalf("%s: Expected error but got success", c.name)
+		}
+	}
+	if _, ok, _ := state.GetWorkspace("web"); ok {
+		t.Fatalf("Expected no workspace named after a repo alias to be saved")
+	}
+}
+
+func TestResources(t *testing.T) {
+	useTempConfigDir(t)
+
+	if err := state.Update(addSession("api_feature")); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	for _, resource := range []state.ResourceInfo{
+		{Name: "db", RepoPath: "/code/api.git", Teardown: "dropdb"},
+		{Name: "stack", RepoPath: "/code/api.git", Teardown: "docker compose down"},
+		{Name: "db", RepoPath: "/code/api.git", Teardown: "dropdb --force"},
+	} {
+		if err := state.RecordResource("api_feature", resource); err != nil {
+			t.Fatalf("Expected success but got error: %s", err)
+		}
+	}
+	if err := state.RecordResource("missing", state.ResourceInfo{Name: "db"}); err == nil {
+		t.Fatalf("Expected an error recording a resource of an unregistered session")
+	}
+
+	session, _, _ := state.GetSession("api_feature")
+	if len(session.Resources) != 2 || session.Resources[0].Name != "stack" || session.Resources[1].Teardown != "dropdb --force" {
+		t.Fatalf("Expected stack then the re-recorded db but got %+v", session.Resources)
+	}
+
+	if err := state.ForgetResource("api_feature", "db", "/code/api.git"); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	session, _, _ = state.GetSession("api_feature")
+	if len(session.Resources) != 1 || session.Resources[0].Name != "stack" {
+		t.Fatalf("Expected only stack to be left but got %+v", session.Resources)
+	}
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// JSON encoding of the zero time.Time
+const zeroTime = "0001-01-01T00:00:00Z"
+
+// CurrentVersion is the state schema version written by this build. Bump it and append a
+// migration whenever SessionInfo or State changes shape.
+const CurrentVersion = 9
+
+// migrations[i] upgrades raw state from version i+1 to i+2. They work on the decoded JSON
+// rather than State, since the structs only describe the current schema.
+var migrations = []func(raw map[string]any) error{
+	migrateV1ToV2,
+	migrateV2ToV3,
+	migrateV3ToV4,
+	migrateV4ToV5,
+	migrateV5ToV6,
+	migrateV6ToV7,
+	migrateV7ToV8,
+	migrateV8ToV9,
+}
+
+type NewerVersionError struct {
+	Version int
+}
+
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("state file was written by a newer twt (schema version %d, this build supports %d) - upgrade twt to modify it", e.Version, CurrentVersion)
+}
+
+// rawVersion reads the schema version. Version 1 wrote it as the string "1.0".
+func rawVersion(raw map[string]any) (int, error) {
+	switch v := raw["version"].(type) {
+	case nil:
+		return 1, nil
+	case float64:
+		return int(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid state version %q", v)
+		}
+		return int(f), nil
+	default:
+		return 0, fmt.Errorf("invalid state version %v", v)
+	}
+}
+
+// migrate upgrades raw state to CurrentVersion. State from a newer build is left untouched.
+func migrate(raw map[string]any) (int, error) {
+	version, err := rawVersion(raw)
+	if err != nil {
+		return 0, err
+	}
+	if version > CurrentVersion {
+		return version, nil
+	}
+
+	for ; version < CurrentVersion; version++ {
+		if err := migrations[version-1](raw); err != nil {
+			return 0, fmt.Errorf("failed to migrate state from version %d: %w", version, err)
+		}
+	}
+	raw["version"] = CurrentVersion
+	return version, nil
+}
+
+func rawSessions(raw map[string]any) map[string]any {
+	sessions, ok := raw["sessions"].(map[string]any)
+	if !ok {
+		sessions = make(map[string]any)
+		raw["sessions"] = sessions
+	}
+	return sessions
+}
+
+// migrateV1ToV2 switches the version to an integer and backfills fields that version 1
+// could leave empty: the repo name, and last access which was never updated.
+func migrateV1ToV2(raw map[string]any) error {
+	for name, value := range rawSessions(raw) {
+		session, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("session %s isn't an object", name)
+		}
+
+		if repoName, _ := session["repo_name"].(string); repoName == "" {
+			if repoPath, ok := session["repo_path"].(string); ok && repoPath != "" {
+				session["repo_name"] = filepath.Base(repoPath)
+			}
+		}
+		if lastAccessed, _ := session["last_accessed"].(string); lastAccessed == "" || lastAccessed == zeroTime {
+			session["last_accessed"] = session["created_at"]
+		}
+	}
+	return nil
+}
+
+// migrateV2ToV3 adds saved session layouts. They're optional so existing sessions need no
+// changes, the version bump only stops older builds from dropping layouts on write.
+func migrateV2ToV3(raw map[string]any) error {
+	return nil
+}
+
+// migrateV3ToV4 adds the hibernated flag, which defaults to false.
+func migrateV3ToV4(raw map[string]any) error {
+	return nil
+}
+
+// migrateV4ToV5 adds the repo registry, seeded with the repo of every registered session.
+func migrateV4ToV5(raw map[string]any) error {
+	repos := make(map[string]any)
+	aliases := make(map[string]bool)
+	for name, value := range rawSessions(raw) {
+		session, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("session %s isn't an object", name)
+		}
+		repoPath, _ := session["repo_path"].(string)
+		if repoPath == "" || repos[repoPath] != nil {
+			continue
+		}
+
+		alias := uniqueAlias(DefaultRepoAlias(repoPath), func(alias string) bool { return aliases[alias] })
+		aliases[alias] = true
+		repos[repoPath] = map[string]any{
+			"path":     repoPath,
+			"alias":    alias,
+			"added_at": session["created_at"],
+		}
+	}
+	raw["repos"] = repos
+	return nil
+}
+
+// migrateV5ToV6 adds workspaces. Workspace sessions only add optional fields.
+func migrateV5ToV6(raw map[string]any) error {
+	if _, ok := raw["workspaces"]; !ok {
+		raw["workspaces"] = map[string]any{}
+	}
+	return nil
+}
+
+// migrateV6ToV7 adds session kinds. Every session registered so far is a worktree session.
+func migrateV6ToV7(raw map[string]any) error {
+	for name, value := range rawSessions(raw) {
+		session, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("session %s isn't an object", name)
+		}
+		if _, ok := session["kind"]; !ok {
+			session["kind"] = "worktree"
+		}
+	}
+	return nil
+}
+
+// migrateV7ToV8 adds the resources set up for a session's worktrees. They're optional, the
+// version bump stops older builds from dropping them on write, leaving them never torn down.
+func migrateV7ToV8(raw map[string]any) error {
+	return nil
+}
+
+// migrateV8ToV9 adds whether a resource's teardown brings its compose project down. Resources
+// recorded before don't say so, so rm brings the project down itself too, which is harmless.
+func migrateV8ToV9(raw map[string]any) error {
+	return nil
+}
@@ -0,0 +1,204 @@
+package state_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/j-clemons/twt/internal/state"
+)
+
+func TestMigrations(t *testing.T) {
+	createdAt := "2024-05-01T10:00:00Z"
+	cases := []struct {
+		name               string
+		input              string
+		expectedRepoName   string
+		expectedAccessed   string
+		expectedWindows    int
+		expectedHibernated bool
+		expectedRepoAlias  string
+		// Worktree unless set
+		expectedKind      state.SessionKind
+		expectedResources int
+		// Whether the first resource brings the compose project down
+		expectedComposeDown bool
+	}{
+		{
+			name: "Version 1 backfills repo name and last access",
+			input: `{"version": "1.0", "sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "repo_name": "", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "0001-01-01T00:00:00Z"}}}`,
+			expectedRepoName:  "repo.git",
+			expectedAccessed:  createdAt,
+			expectedRepoAlias: "repo",
+		},
+		{
+			name: "Missing version is treated as version 1",
+			input: `{"sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z"}}}`,
+			expectedRepoName:  "repo.git",
+			expectedAccessed:  createdAt,
+			expectedRepoAlias: "repo",
+		},
+		{
+			name: "Version 2 keeps existing fields",
+			input: `{"version": 2, "sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z"}}}`,
+			expectedRepoName:  "custom",
+			expectedAccessed:  "2024-06-01T10:00:00Z",
+			expectedRepoAlias: "repo",
+		},
+		{
+			name: "Version 3 keeps saved layouts",
+			input: `{"version": 3, "sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z",
+				"layout": {"windows": [{"name": "editor", "layout": "tiled", "panes": [{"dir": "/code"}]}]}}}}`,
+			expectedRepoName:  "custom",
+			expectedAccessed:  "2024-06-01T10:00:00Z",
+			expectedWindows:   1,
+			expectedRepoAlias: "repo",
+		},
+		{
+			name: "Version 4 keeps hibernated sessions",
+			input: `{"version": 4, "sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z",
+				"layout": {"windows": [{"name": "editor", "layout": "tiled", "panes": [{"dir": "/code"}]}]},
+				"hibernated": true}}}`,
+			expectedRepoName:   "custom",
+			expectedAccessed:   "2024-06-01T10:00:00Z",
+			expectedWindows:    1,
+			expectedHibernated: true,
+			expectedRepoAlias:  "repo",
+		},
+		{
+			name: "Version 5 keeps repo aliases",
+			input: `{"version": 5, "sessions": {"repo_main": {
+				"name": "repo_main", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z"}},
+				"repos": {"/code/repo.git": {"path": "/code/repo.git", "alias": "r", "added_at": "2024-05-01T10:00:00Z"}}}`,
+			expectedRepoName:  "custom",
+			expectedAccessed:  "2024-06-01T10:00:00Z",
+			expectedRepoAlias: "r",
+		},
+		{
+			name: "Version 7 keeps common sessions",
+			input: `{"version": 7, "sessions": {"repo_main": {
+				"name": "repo_main", "kind": "common", "repo_path": "/code/repo.git", "repo_name": "custom",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z"}},
+				"repos": {"/code/repo.git": {"path": "/code/repo.git", "alias": "repo", "added_at": "2024-05-01T10:00:00Z"}}}`,
+			expectedRepoName:  "custom",
+			expectedAccessed:  "2024-06-01T10:00:00Z",
+			expectedRepoAlias: "repo",
+			expectedKind:      state.KindCommon,
+		},
+		{
+			name: "Version 8 keeps resources",
+			input: `{"version": 8, "sessions": {"repo_main": {
+				"name": "repo_main", "kind": "worktree", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z",
+				"resources": [{"name": "db", "repo_path": "/code/repo.git", "dir": "/code/repo.git/main", "teardown": "dropdb main"}]}},
+				"repos": {"/code/repo.git": {"path": "/code/repo.git", "alias": "repo", "added_at": "2024-05-01T10:00:00Z"}}}`,
+			expectedRepoName:  "custom",
+			expectedAccessed:  "2024-06-01T10:00:00Z",
+			expectedRepoAlias: "repo",
+			expectedResources: 1,
+		},
+		{
+			name: "Version 9 keeps compose down",
+			input: `{"version": 9, "sessions": {"repo_main": {
+				"name": "repo_main", "kind": "worktree", "repo_path": "/code/repo.git", "repo_name": "custom", "branch": "main",
+				"created_at": "2024-05-01T10:00:00Z", "last_accessed": "2024-06-01T10:00:00Z",
+				"resources": [{"name": "compose", "repo_path": "/code/repo.git", "dir": "/code/repo.git/main", "teardown": "docker compose down", "compose_down": true}]}},
+				"repos": {"/code/repo.git": {"path": "/code/repo.git", "alias": "repo", "added_at": "2024-05-01T10:00:00Z"}}}`,
+			expectedRepoName:    "custom",
+			expectedAccessed:    "2024-06-01T10:00:00Z",
+			expectedRepoAlias:   "repo",
+			expectedResources:   1,
+			expectedComposeDown: true,
+		},
+	}
+
+	for _, c := range cases {
+		stateFile := useTempConfigDir(t)
+		if err := os.WriteFile(stateFile, []byte(c.input), 0644); err != nil {
+			t.Fatalf("%s: Couldn't write state file: %s", c.name, err)
+		}
+
+		s, err := state.LoadState()
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if s.Version != state.CurrentVersion {
+			t.Fatalf("%s: Expected version %d but got %d", c.name, state.CurrentVersion, s.Version)
+		}
+
+		session := s.Sessions["repo_main"]
+		if session.RepoName != c.expectedRepoName {
+			t.Fatalf("%s: Expected repo name %s but got %s", c.name, c.expectedRepoName, session.RepoName)
+		}
+		expectedAccessed, _ := time.Parse(time.RFC3339, c.expectedAccessed)
+		if !session.LastAccessed.Equal(expectedAccessed) {
+			t.Fatalf("%s: Expected last accessed %s but got %s", c.name, expectedAccessed, session.LastAccessed)
+		}
+		windows := 0
+		if session.Layout != nil {
+			windows = len(session.Layout.Windows)
+		}
+		if windows != c.expectedWindows {
+			t.Fatalf("%s: Expected %d saved windows but got %d", c.name, c.expectedWindows, windows)
+		}
+		if session.Hibernated != c.expectedHibernated {
+			t.Fatalf("%s: Expected hibernated %t but got %t", c.name, c.expectedHibernated, session.Hibernated)
+		}
+		expectedKind := c.expectedKind
+		if expectedKind == "" {
+			expectedKind = state.KindWorktree
+		}
+		if session.Kind != expectedKind {
+			t.Fatalf("%s: Expected kind %s but got %s", c.name, expectedKind, session.Kind)
+		}
+		if len(session.Resources) != c.expectedResources {
+			t.Fatalf("%s: Expected %d resources but got %d", c.name, c.expectedResources, len(session.Resources))
+		}
+		if len(session.Resources) > 0 && session.Resources[0].ComposeDown != c.expectedComposeDown {
+			t.Fatalf("%s: Expected compose down %t but got %t", c.name, c.expectedComposeDown, session.Resources[0].ComposeDown)
+		}
+		if alias := s.Repos["/code/repo.git"].Alias; alias != c.expectedRepoAlias {
+			t.Fatalf("%s: Expected repo alias %s but got %s", c.name, c.expectedRepoAlias, alias)
+		}
+	}
+}
+
+func TestRefuseToOverwriteNewerState(t *testing.T) {
+	stateFile := useTempConfigDir(t)
+
+	newer := `{"version": 99, "sessions": {"repo_main": {"name": "repo_main", "future_field": true}}}`
+	if err := os.WriteFile(stateFile, []byte(newer), 0644); err != nil {
+		t.Fatalf("Couldn't write state file: %s", err)
+	}
+
+	s, err := state.LoadState()
+	if err != nil {
+		t.Fatalf("Expected newer state to be readable but got error: %s", err)
+	}
+	if _, ok := s.Sessions["repo_main"]; !ok {
+		t.Fatalf("Expected session from newer state to be read")
+	}
+
+	err = state.Update(addSession("other"))
+	var newerErr *state.NewerVersionError
+	if !errors.As(err, &newerErr) {
+		t.Fatalf("Expected a newer version error but got %v", err)
+	}
+
+	data, _ := os.ReadFile(stateFile)
+	if string(data) != newer {
+		t.Fatalf("Expected newer state file to be left untouched but got %s", data)
+	}
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/j-clemons/twt/internal/fsutil"
+)
+
+const backupFileSuffix = ".bak"
+
+type corruptStateError struct {
+	err error
+}
+
+func (e *corruptStateError) Error() string {
+	return fmt.Sprintf("failed to parse state file: %v", e.err)
+}
+
+func (e *corruptStateError) Unwrap() error {
+	return e.err
+}
+
+func isCorrupt(err error) bool {
+	var corrupt *corruptStateError
+	return errors.As(err, &corrupt)
+}
+
+func readStateFile(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseState(data)
+}
+
+func parseState(data []byte) (*State, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, &corruptStateError{err: err}
+	}
+
+	version, err := migrate(raw)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migrated state: %w", err)
+	}
+
+	// State from a newer build is decoded best effort so it can still be read, keeping its
+	// version so writes are refused
+	var state State
+	if err := json.Unmarshal(migrated, &state); err != nil {
+		return nil, &corruptStateError{err: err}
+	}
+	state.Version = version
+	if state.Sessions == nil {
+		state.Sessions = make(map[string]SessionInfo)
+	}
+	if state.Repos == nil {
+		state.Repos = make(map[string]RepoInfo)
+	}
+	if state.Workspaces == nil {
+		state.Workspaces = make(map[string]Workspace)
+	}
+	return &state, nil
+}
+
+// readState reads the state file, falling back to a new state if it doesn't exist. A corrupt
+// file is only reported, recovery needs the exclusive lock and is left to recoverState.
+func readState(stateFile string) (*State, error) {
+	state, err := readStateFile(stateFile)
+	if os.IsNotExist(err) {
+		return NewState(), nil
+	}
+	if err != nil && !isCorrupt(err) {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return state, err
+}
+
+// recoverState replaces a corrupt state file with the last good backup, or an empty state
+// if there's no usable backup. The corrupt file is kept next to it for inspection. Must be
+// called with the exclusive lock held.
+func recoverState(stateFile string) (*State, error) {
+	state, err := readState(stateFile)
+	if err == nil {
+		// Recovered by another process while we waited for the lock
+		return state, nil
+	}
+
+	corruptFile := fmt.Sprintf("%s.corrupt-%s", stateFile, time.Now().Format("20060102-150405"))
+	if err := os.Rename(stateFile, corruptFile); err != nil {
+		return nil, fmt.Errorf("failed to move corrupt state file aside: %w", err)
+	}
+
+	state, backupErr := readStateFile(stateFile + backupFileSuffix)
+	if backupErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: state file was corrupt and no usable backup exists, starting fresh. Corrupt file kept at %s\n", corruptFile)
+		state = NewState()
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: state file was corrupt, restored the last good backup. Corrupt file kept at %s\n", corruptFile)
+	}
+
+	if err := writeState(stateFile, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// writeState persists state by writing a temp file and renaming it into place, so a crash
+// never leaves a partially written state file. The previous file is kept as a backup if
+// it's valid. Must be called with the exclusive lock held.
+func writeState(stateFile string, state *State) error {
+	if state.Version > CurrentVersion {
+		return &NewerVersionError{Version: state.Version}
+	}
+	state.Version = CurrentVersion
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if previous, err := os.ReadFile(stateFile); err == nil {
+		if onDisk, err := parseState(previous); err == nil {
+			if onDisk.Version > CurrentVersion {
+				return &NewerVersionError{Version: onDisk.Version}
+			}
+			if err := fsutil.WriteFileAtomic(stateFile+backupFileSuffix, previous, 0644); err != nil {
+				return fmt.Errorf("failed to back up state file: %w", err)
+			}
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
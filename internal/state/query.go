@@ -1,6 +1,7 @@
 package state
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/j-clemons/twt/internal/git"
@@ -46,12 +47,57 @@ func ListAllSessions() ([]SessionInfo, error) {
 		sessions = append(sessions, session)
 	}
 
-	sort.Slice(sessions, func(i, j int) bool {
+	SortByCreated(sessions)
+
+	return sessions, nil
+}
+
+// SortByCreated groups sessions by repo, newest first within each repo.
+func SortByCreated(sessions []SessionInfo) {
+	sort.SliceStable(sessions, func(i, j int) bool {
 		if sessions[i].RepoName != sessions[j].RepoName {
 			return sessions[i].RepoName < sessions[j].RepoName
 		}
 		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
 	})
+}
+
+// ListRecentSessions returns sessions most recently accessed first, for the repo at repoPath
+// or for all repos if repoPath is empty.
+func ListRecentSessions(repoPath string) ([]SessionInfo, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
 
+	var sessions []SessionInfo
+	for _, session := range state.Sessions {
+		if repoPath == "" || session.RepoPath == repoPath {
+			sessions = append(sessions, session)
+		}
+	}
+
+	SortByRecent(sessions)
 	return sessions, nil
 }
+
+func SortByRecent(sessions []SessionInfo) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastAccessed.After(sessions[j].LastAccessed)
+	})
+}
+
+// MostRecentActive returns the most recently accessed running session not in exclude.
+func MostRecentActive(repoPath string, exclude ...string) (SessionInfo, bool) {
+	sessions, err := ListRecentSessions(repoPath)
+	if err != nil {
+		return SessionInfo{}, false
+	}
+
+	for _, session := range sessions {
+		if session.IsActive() && !slices.Contains(exclude, session.Name) {
+			return session, true
+		}
+	}
+	return SessionInfo{}, false
+}
@@ -0,0 +1,136 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRepoAlias is the alias a repo gets when it's registered: its dir name, without a
+// .git suffix.
+func DefaultRepoAlias(repoPath string) string {
+	return strings.TrimSuffix(filepath.Base(repoPath), ".git")
+}
+
+// uniqueAlias returns alias, or alias with a number appended if it's taken.
+func uniqueAlias(alias string, taken func(string) bool) string {
+	unique := alias
+	for i := 2; taken(unique); i++ {
+		unique = fmt.Sprintf("%s-%d", alias, i)
+	}
+	return unique
+}
+
+func (s *State) aliasTaken(alias, exceptPath string) bool {
+	for path, repo := range s.Repos {
+		if repo.Alias == alias && path != exceptPath {
+			return true
+		}
+	}
+	return false
+}
+
+// registerRepo adds the repo with its default alias if it isn't registered yet, reporting
+// whether it was added.
+func (s *State) registerRepo(repoPath string) bool {
+	if _, ok := s.Repos[repoPath]; ok || repoPath == "" {
+		return false
+	}
+	alias := uniqueAlias(DefaultRepoAlias(repoPath), func(alias string) bool {
+		return s.aliasTaken(alias, repoPath)
+	})
+	s.Repos[repoPath] = RepoInfo{Path: repoPath, Alias: alias, AddedAt: time.Now()}
+	return true
+}
+
+// findRepo looks a repo up by alias, or by path.
+func (s *State) findRepo(aliasOrPath string) (RepoInfo, bool) {
+	for _, repo := range s.Repos {
+		if repo.Alias == aliasOrPath {
+			return repo, true
+		}
+	}
+	repo, ok := s.Repos[filepath.Clean(aliasOrPath)]
+	return repo, ok
+}
+
+// RegisterRepo adds a repo to the registry with its default alias, if it isn't there yet.
+func RegisterRepo(repoPath string) error {
+	return Update(func(state *State) error {
+		if !state.registerRepo(repoPath) {
+			return errUnchanged
+		}
+		return nil
+	})
+}
+
+// AddRepo registers a repo, or changes the alias of a registered one. An empty alias keeps
+// the current or default alias.
+func AddRepo(repoPath, alias string) (RepoInfo, error) {
+	var added RepoInfo
+	err := Update(func(state *State) error {
+		state.registerRepo(repoPath)
+		repo := state.Repos[repoPath]
+		if alias != "" {
+			if state.aliasTaken(alias, repoPath) {
+				return fmt.Errorf("alias %s is already used by another repo", alias)
+			}
+			repo.Alias = alias
+		}
+		state.Repos[repoPath] = repo
+		added = repo
+		return nil
+	})
+	return added, err
+}
+
+// RemoveRepo drops a repo from the registry by alias or path, unless a workspace uses it.
+// Its sessions are left alone.
+func RemoveRepo(aliasOrPath string) (RepoInfo, error) {
+	var removed RepoInfo
+	err := Update(func(state *State) error {
+		repo, ok := state.findRepo(aliasOrPath)
+		if !ok {
+			return fmt.Errorf("no registered repo %s", aliasOrPath)
+		}
+		if used := state.workspacesUsing(repo.Path); len(used) > 0 {
+			return fmt.Errorf("repo %s is in workspaces %s - remove it from them first", repo.Alias, strings.Join(used, ", "))
+		}
+		delete(state.Repos, repo.Path)
+		removed = repo
+		return nil
+	})
+	return removed, err
+}
+
+// LookupRepo finds a registered repo by alias or path.
+func LookupRepo(aliasOrPath string) (RepoInfo, bool, error) {
+	state, err := LoadState()
+	if err != nil {
+		return RepoInfo{}, false, err
+	}
+	repo, ok := state.findRepo(aliasOrPath)
+	return repo, ok, nil
+}
+
+// ListRepos returns the registered repos sorted by alias.
+func ListRepos() ([]RepoInfo, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]RepoInfo, 0, len(state.Repos))
+	for _, repo := range state.Repos {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Alias != repos[j].Alias {
+			return repos[i].Alias < repos[j].Alias
+		}
+		return repos[i].Path < repos[j].Path
+	})
+	return repos, nil
+}
@@ -0,0 +1,45 @@
+package state
+
+import "fmt"
+
+// RecordResource records a resource set up for a session, replacing any of the same name
+// from the same repo.
+func RecordResource(sessionName string, resource ResourceInfo) error {
+	return Update(func(state *State) error {
+		session, exists := state.Sessions[sessionName]
+		if !exists {
+			return fmt.Errorf("session %s isn't managed by twt", sessionName)
+		}
+		resources := session.Resources[:0:0]
+		for _, recorded := range session.Resources {
+			if recorded.Name != resource.Name || recorded.RepoPath != resource.RepoPath {
+				resources = append(resources, recorded)
+			}
+		}
+		session.Resources = append(resources, resource)
+		state.Sessions[sessionName] = session
+		return nil
+	})
+}
+
+// ForgetResource removes the record of a resource of a session, once it's torn down.
+func ForgetResource(sessionName, name, repoPath string) error {
+	return Update(func(state *State) error {
+		session, exists := state.Sessions[sessionName]
+		if !exists {
+			return errUnchanged
+		}
+		resources := session.Resources[:0:0]
+		for _, recorded := range session.Resources {
+			if recorded.Name != name || recorded.RepoPath != repoPath {
+				resources = append(resources, recorded)
+			}
+		}
+		if len(resources) == len(session.Resources) {
+			return errUnchanged
+		}
+		session.Resources = resources
+		state.Sessions[sessionName] = session
+		return nil
+	})
+}
@@ -2,35 +2,99 @@ package state
 
 import (
 	"time"
+
+	"github.com/j-clemons/twt/internal/tmux"
 )
 
 type SessionStatus string
 
 const (
-	StatusActive   SessionStatus = "active"
-	StatusInactive SessionStatus = "inactive"
+	StatusActive     SessionStatus = "active"
+	StatusInactive   SessionStatus = "inactive"
+	StatusHibernated SessionStatus = "hibernated"
+)
+
+// SessionKind is what a session is for.
+type SessionKind string
+
+const (
+	// A session for a worktree of a branch, or of a workspace
+	KindWorktree SessionKind = "worktree"
+	// A repo's session in its common files dir, which isn't a worktree
+	KindCommon SessionKind = "common"
 )
 
 type SessionInfo struct {
-	Name         string        `json:"name"`
-	RepoPath     string        `json:"repo_path"`
-	RepoName     string        `json:"repo_name"`
-	Branch       string        `json:"branch"`
-	WorktreePath string        `json:"worktree_path"`
-	CreatedAt    time.Time     `json:"created_at"`
-	LastAccessed time.Time     `json:"last_accessed"`
-	Status       SessionStatus `json:"-"`
+	Name         string       `json:"name"`
+	Kind         SessionKind  `json:"kind"`
+	RepoPath     string       `json:"repo_path"`
+	RepoName     string       `json:"repo_name"`
+	Branch       string       `json:"branch"`
+	WorktreePath string       `json:"worktree_path"`
+	CreatedAt    time.Time    `json:"created_at"`
+	LastAccessed time.Time    `json:"last_accessed"`
+	Layout       *tmux.Layout `json:"layout,omitempty"`
+	// Stopped by twt to save resources, brought back with its layout by go or restore
+	Hibernated bool `json:"hibernated,omitempty"`
+	// Set for sessions spanning a worktree of the branch in each repo of a workspace. RepoPath
+	// and WorktreePath are then those of its first repo.
+	Workspace string `json:"workspace,omitempty"`
+	// Worktree of each repo of a workspace session, keyed by repo path
+	Worktrees map[string]string `json:"worktrees,omitempty"`
+	// Resources set up for the session's worktrees, in the order they were set up
+	Resources []ResourceInfo `json:"resources,omitempty"`
+	Status    SessionStatus  `json:"-"`
+	Attached  int            `json:"-"`
+	Dirty     bool           `json:"-"`
+}
+
+// ResourceInfo is a resource from a repo's provision.json set up for a worktree, e.g. a
+// compose stack, recorded so it's torn down when the worktree is removed.
+type ResourceInfo struct {
+	Name string `json:"name"`
+	// The repo whose manifest declared it
+	RepoPath string `json:"repo_path"`
+	// The worktree it was set up in
+	Dir string `json:"dir"`
+	// Command undoing the setup, run in Dir; empty if there's nothing to undo
+	Teardown string `json:"teardown,omitempty"`
+	// Whether Teardown brings the worktree's compose project down, so rm doesn't itself
+	ComposeDown bool      `json:"compose_down,omitempty"`
+	SetupAt     time.Time `json:"setup_at"`
+}
+
+// RepoInfo is a repo twt knows about, so commands can be pointed at it from anywhere with
+// --repo.
+type RepoInfo struct {
+	// Base dir of the repo, i.e. the bare repo
+	Path    string    `json:"path"`
+	Alias   string    `json:"alias,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Workspace is a named set of registered repos worked on together, see
+// 'twt go --workspace'.
+type Workspace struct {
+	Name string `json:"name"`
+	// Repo paths, in the order their windows are opened
+	Repos     []string  `json:"repos"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type State struct {
-	Version  string                 `json:"version"`
+	Version  int                    `json:"version"`
 	Sessions map[string]SessionInfo `json:"sessions"`
+	// Keyed by path
+	Repos      map[string]RepoInfo  `json:"repos"`
+	Workspaces map[string]Workspace `json:"workspaces"`
 }
 
 func NewState() *State {
 	return &State{
-		Version:  "1.0",
-		Sessions: make(map[string]SessionInfo),
+		Version:    CurrentVersion,
+		Sessions:   make(map[string]SessionInfo),
+		Repos:      make(map[string]RepoInfo),
+		Workspaces: make(map[string]Workspace),
 	}
 }
 
@@ -45,3 +109,9 @@ func (s *SessionInfo) Age() time.Duration {
 func (s *SessionInfo) TimeSinceAccessed() time.Duration {
 	return time.Since(s.LastAccessed)
 }
+
+// IsCommon reports whether the session works in its repo's common files dir rather than a
+// worktree.
+func (s *SessionInfo) IsCommon() bool {
+	return s.Kind == KindCommon
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+// Upper bound on concurrent git processes when checking worktrees.
+const maxStatusWorkers = 8
+
+// refreshStatus sets the active status and attached client count of every session from a
+// single tmux call, instead of one has-session call per session.
+func refreshStatus(sessions map[string]SessionInfo) {
+	clients := tmux.ListAttachedClients()
+
+	for name, session := range sessions {
+		attached, running := clients[name]
+		if running {
+			session.Status = StatusActive
+		} else if session.Hibernated {
+			session.Status = StatusHibernated
+		} else {
+			session.Status = StatusInactive
+		}
+		session.Attached = attached
+		sessions[name] = session
+	}
+}
+
+// DetectDirty sets the dirty flag on each session by checking its worktree for uncommitted
+// changes. Worktrees are checked concurrently. Common sessions have no worktree to check.
+func DetectDirty(sessions []SessionInfo) {
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, maxStatusWorkers)
+
+	for i := range sessions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			if !sessions[i].IsCommon() {
+				sessions[i].Dirty = git.IsDirty(sessions[i].WorktreePath)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
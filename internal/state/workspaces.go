@@ -0,0 +1,88 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SaveWorkspace defines a workspace as the repos at repoPaths, registering any that aren't
+// yet. An existing workspace of the same name is redefined. Its name can't contain / or be
+// a repo's alias.
+func SaveWorkspace(name string, repoPaths []string) (Workspace, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return Workspace{}, fmt.Errorf("invalid workspace name %q, it can't be empty or contain /", name)
+	}
+	var saved Workspace
+	err := Update(func(state *State) error {
+		workspace, exists := state.Workspaces[name]
+		if !exists {
+			workspace = Workspace{Name: name, CreatedAt: time.Now()}
+		}
+		workspace.Repos = repoPaths
+		for _, repoPath := range repoPaths {
+			state.registerRepo(repoPath)
+		}
+		// Either could be meant where a repo or workspace is expected
+		if state.aliasTaken(name, "") {
+			return fmt.Errorf("%s is already a repo alias, give the workspace another name", name)
+		}
+		state.Workspaces[name] = workspace
+		saved = workspace
+		return nil
+	})
+	return saved, err
+}
+
+// RemoveWorkspace forgets a workspace. Sessions opened for it are left alone.
+func RemoveWorkspace(name string) error {
+	return Update(func(state *State) error {
+		if _, ok := state.Workspaces[name]; !ok {
+			return fmt.Errorf("no workspace %s", name)
+		}
+		delete(state.Workspaces, name)
+		return nil
+	})
+}
+
+func GetWorkspace(name string) (Workspace, bool, error) {
+	state, err := LoadState()
+	if err != nil {
+		return Workspace{}, false, err
+	}
+	workspace, ok := state.Workspaces[name]
+	return workspace, ok, nil
+}
+
+// ListWorkspaces returns the workspaces sorted by name.
+func ListWorkspaces() ([]Workspace, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]Workspace, 0, len(state.Workspaces))
+	for _, workspace := range state.Workspaces {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	return workspaces, nil
+}
+
+// workspacesUsing returns the names of the workspaces that include the repo.
+func (s *State) workspacesUsing(repoPath string) []string {
+	var names []string
+	for _, workspace := range s.Workspaces {
+		for _, path := range workspace.Repos {
+			if path == repoPath {
+				names = append(names, workspace.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,3 @@
+#!/bin/bash
+
+echo "Enter your scripts here"
\ No newline at end of file
@@ -0,0 +1,8 @@
+#!/bin/bash
+# Runs in each new worktree before its session opens. A non-zero exit undoes 'twt go'.
+set -euo pipefail
+
+common="$(git rev-parse --git-common-dir)/common"
+
+# Start from the shared example env, keeping any .env the worktree already has
+[ -f .env ] || cp "$common/env/.env.example" .env
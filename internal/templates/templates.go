@@ -0,0 +1,248 @@
+package templates
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+// DirName is the dir in the twt config dir holding user templates, one dir per template.
+const DirName = "templates"
+
+const Default = "default"
+
+//go:embed all:builtin
+var builtin embed.FS
+
+// Files several built-in templates have in common, a dir per layer
+//
+//go:embed all:shared
+var shared embed.FS
+
+// builtinLayers are the shared layers each built-in template is made of, in order, before its
+// own files.
+var builtinLayers = map[string][]string{
+	"go":      {"env"},
+	"node":    {"env"},
+	"compose": {"env"},
+}
+
+const (
+	filePerm   = 0600
+	scriptPerm = 0700
+)
+
+// Template is a set of files to scaffold a common files dir from.
+type Template struct {
+	Name string
+	// Where the template comes from: "built-in", or a dir or git repo path
+	Source string
+	files  func() (map[string][]byte, error)
+}
+
+// File is a file of a template, at a path relative to the common files dir.
+type File struct {
+	Path    string
+	Content []byte
+	Mode    fs.FileMode
+}
+
+// Files returns the template's files sorted by path. Anything under scripts/ is made
+// executable.
+func (t Template) Files() ([]File, error) {
+	contents, err := t.files()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read template %s: %w", t.Name, err)
+	}
+
+	files := make([]File, 0, len(contents))
+	for filePath, content := range contents {
+		mode := fs.FileMode(filePerm)
+		if strings.HasPrefix(filePath, "scripts/") {
+			mode = scriptPerm
+		}
+		files = append(files, File{Path: filePath, Content: content, Mode: mode})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// List returns the built-in templates, then the user's: dirs in the templates dir of the
+// twt config dir, and the dirs or git repos named in common.templates in the config. A user
+// template with the name of a built-in one replaces it.
+func List() ([]Template, error) {
+	byName := make(map[string]Template)
+
+	entries, err := fs.ReadDir(builtin, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		byName[name] = Template{
+			Name:   name,
+			Source: "built-in",
+			files: func() (map[string][]byte, error) {
+				files := make(map[string][]byte)
+				for _, layer := range builtinLayers[name] {
+					if err := readEmbedded(files, shared, path.Join("shared", layer)); err != nil {
+						return nil, err
+					}
+				}
+				return files, readEmbedded(files, builtin, path.Join("builtin", name))
+			},
+		}
+	}
+
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	userDir := filepath.Join(configDir, DirName)
+	if entries, err := os.ReadDir(userDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				byName[entry.Name()] = fromPath(entry.Name(), filepath.Join(userDir, entry.Name()))
+			}
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	for name, templatePath := range cfg.Common.Templates {
+		byName[name] = fromPath(name, templatePath)
+	}
+
+	templates := make([]Template, 0, len(byName))
+	for _, template := range byName {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if (templates[i].Source == "built-in") != (templates[j].Source == "built-in") {
+			return templates[i].Source == "built-in"
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Get returns the template with the given name.
+func Get(name string) (Template, error) {
+	templates, err := List()
+	if err != nil {
+		return Template{}, err
+	}
+	var names []string
+	for _, template := range templates {
+		if template.Name == name {
+			return template, nil
+		}
+		names = append(names, template.Name)
+	}
+	return Template{}, fmt.Errorf("no template %s, available: %s", name, strings.Join(names, ", "))
+}
+
+// fromPath is a user template at dir, or in the committed files of a git repo at dir, so a
+// team can share templates through a repo.
+func fromPath(name, dir string) Template {
+	return Template{
+		Name:   name,
+		Source: dir,
+		files: func() (map[string][]byte, error) {
+			dir, err := utils.ExpandHome(dir)
+			if err != nil {
+				return nil, err
+			}
+			if isGitRepo(dir) {
+				return readGitHead(dir)
+			}
+			return readFS(os.DirFS(dir))
+		},
+	}
+}
+
+// readEmbedded adds the files in dir of fsys to files, replacing those at the same paths.
+func readEmbedded(files map[string][]byte, fsys embed.FS, dir string) error {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+	layer, err := readFS(sub)
+	if err != nil {
+		return err
+	}
+	for filePath, content := range layer {
+		files[filePath] = content
+	}
+	return nil
+}
+
+func readFS(fsys fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		files[filePath] = content
+		return nil
+	})
+	return files, err
+}
+
+func isGitRepo(dir string) bool {
+	out, err := command.Output(dir, "git", "rev-parse", "--git-dir")
+	if err != nil {
+		return false
+	}
+	// A plain dir inside some other repo isn't a template repo
+	gitDir := strings.TrimSpace(string(out))
+	return gitDir == "." || gitDir == ".git"
+}
+
+// readGitHead reads the files committed at HEAD in the repo at dir.
+func readGitHead(dir string) (map[string][]byte, error) {
+	out, err := command.Output(dir, "git", "ls-tree", "-r", "-z", "--name-only", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, filePath := range strings.Split(strings.TrimRight(string(out), "\x00"), "\x00") {
+		if filePath == "" {
+			continue
+		}
+		content, err := command.Output(dir, "git", "show", "HEAD:"+filePath)
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = content
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no files committed")
+	}
+	return files, nil
+}
@@ -0,0 +1,66 @@
+package templates_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/templates"
+)
+
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	userTemplate := filepath.Join(dir, "twt", templates.DirName, "go")
+	if err := os.MkdirAll(filepath.Join(userTemplate, "scripts", "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userTemplate, "scripts", "go", "post.sh"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	postScripts := make(map[string]string)
+	for _, name := range []string{"default", "node", "compose"} {
+		template, err := templates.Get(name)
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", name, err)
+		}
+		files, err := template.Files()
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", name, err)
+		}
+		found := false
+		for _, file := range files {
+			if file.Path == "scripts/go/post.sh" {
+				found = file.Mode&0100 != 0
+				postScripts[name] = string(file.Content)
+			}
+		}
+		if !found {
+			t.Fatalf("%s: Expected an executable scripts/go/post.sh", name)
+		}
+	}
+
+	// node and compose share their env setup hook
+	if postScripts["node"] != postScripts["compose"] || !strings.Contains(postScripts["node"], ".env.example") {
+		t.Fatalf("Expected node and compose to share the env post.sh but got %q and %q", postScripts["node"], postScripts["compose"])
+	}
+	if postScripts["default"] == postScripts["node"] {
+		t.Fatalf("Expected the default template to keep its own post.sh")
+	}
+
+	template, err := templates.Get("go")
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if template.Source != userTemplate {
+		t.Fatalf("Expected the user template to replace the built-in one but got %s", template.Source)
+	}
+
+	if _, err := templates.Get("missing"); err == nil {
+		t.Fatalf("Expected an error for a missing template")
+	}
+}
@@ -0,0 +1,59 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/command"
+)
+
+// Slots twt uses in tmux hook arrays, so installing is idempotent and uninstalling leaves
+// hooks set by the user alone.
+const (
+	TouchHookIndex    = 77
+	AutosaveHookIndex = 78
+)
+
+type Hook struct {
+	Event   string
+	Index   int
+	Command string
+}
+
+func (h Hook) name() string {
+	return fmt.Sprintf("%s[%d]", h.Event, h.Index)
+}
+
+func SetHook(hook Hook) error {
+	_, stderr := command.Run("tmux", "set-hook", "-g", hook.name(), hook.Command)
+	if len(stderr) > 0 {
+		return fmt.Errorf("couldn't set tmux hook %s: %v", hook.Event, stderr)
+	}
+	return nil
+}
+
+func UnsetHook(hook Hook) error {
+	_, stderr := command.Run("tmux", "set-hook", "-gu", hook.name())
+	if len(stderr) > 0 {
+		return fmt.Errorf("couldn't unset tmux hook %s: %v", hook.Event, stderr)
+	}
+	return nil
+}
+
+// GetHook returns the command currently set in the hook's slot, or an empty string if none
+// is set.
+func GetHook(hook Hook) string {
+	out, _ := command.Run("tmux", "show-hooks", "-g", hook.Event)
+	prefix := hook.name() + " "
+	for _, line := range out {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+// HookConfLine is the tmux.conf equivalent of SetHook, to keep hooks across server restarts.
+func HookConfLine(hook Hook) string {
+	return fmt.Sprintf("set-hook -g '%s' %q", hook.name(), hook.Command)
+}
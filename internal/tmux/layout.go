@@ -0,0 +1,118 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/j-clemons/twt/internal/command"
+)
+
+type Layout struct {
+	Windows []Window  `json:"windows"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+type Window struct {
+	Name string `json:"name"`
+	// tmux layout string, as given by #{window_layout} and taken by select-layout
+	Layout string `json:"layout"`
+	Panes  []Pane `json:"panes"`
+}
+
+type Pane struct {
+	Dir string `json:"dir"`
+	// Command to rerun in the pane, only set for whitelisted commands
+	Command string `json:"command,omitempty"`
+}
+
+const paneFormat = "#{window_index}\t#{window_name}\t#{window_layout}\t#{pane_current_path}\t#{pane_current_command}"
+
+// CaptureLayout records the windows and panes of a session. Pane commands are only kept if
+// they're in allowedCommands, anything else (e.g. shells) restores as a plain shell.
+func CaptureLayout(sessionName string, allowedCommands []string) (*Layout, error) {
+	out, stderr := command.Run("tmux", "list-panes", "-s", "-t", sessionName, "-F", paneFormat)
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("couldn't list panes for session %s: %v", sessionName, stderr)
+	}
+
+	layout := &Layout{SavedAt: time.Now()}
+	lastWindow := ""
+	for _, line := range out {
+		fields := strings.SplitN(line, "\t", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		windowIndex, name, windowLayout, dir, paneCommand := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+		if windowIndex != lastWindow {
+			layout.Windows = append(layout.Windows, Window{Name: name, Layout: windowLayout})
+			lastWindow = windowIndex
+		}
+
+		pane := Pane{Dir: dir}
+		if slices.Contains(allowedCommands, paneCommand) {
+			pane.Command = paneCommand
+		}
+		window := &layout.Windows[len(layout.Windows)-1]
+		window.Panes = append(window.Panes, pane)
+	}
+
+	if len(layout.Windows) == 0 {
+		return nil, fmt.Errorf("session %s has no windows", sessionName)
+	}
+	return layout, nil
+}
+
+// RestoreLayout creates a detached session with the saved windows and panes, falling back
+// to defaultDir for panes whose dir no longer exists. env vars given as KEY=value are set
+// for every pane.
+func RestoreLayout(sessionName string, layout *Layout, defaultDir string, env ...string) error {
+	paneDir := func(pane Pane) string {
+		if info, err := os.Stat(pane.Dir); err == nil && info.IsDir() {
+			return pane.Dir
+		}
+		return defaultDir
+	}
+
+	created := false
+	for _, window := range layout.Windows {
+		if len(window.Panes) == 0 {
+			continue
+		}
+
+		var args []string
+		if !created {
+			args = append([]string{"new-session", "-d", "-s", sessionName, "-n", window.Name, "-c", paneDir(window.Panes[0])}, envArgs(env)...)
+		} else {
+			args = []string{"new-window", "-t", sessionName + ":", "-n", window.Name, "-c", paneDir(window.Panes[0])}
+		}
+		if _, stderr := command.Run("tmux", args...); len(stderr) > 0 {
+			return fmt.Errorf("couldn't create window %s: %v", window.Name, stderr)
+		}
+		created = true
+
+		target := fmt.Sprintf("%s:{end}", sessionName)
+		for _, pane := range window.Panes[1:] {
+			command.Run("tmux", "split-window", "-t", target, "-c", paneDir(pane))
+			// Keep panes evenly sized so later splits have room
+			command.Run("tmux", "select-layout", "-t", target, "tiled")
+		}
+		command.Run("tmux", "select-layout", "-t", target, window.Layout)
+
+		// Pane ids rather than indexes, as pane-base-index may not be 0
+		paneIds, _ := command.Run("tmux", "list-panes", "-t", target, "-F", "#{pane_id}")
+		for j, pane := range window.Panes {
+			if pane.Command != "" && j < len(paneIds) {
+				SendKeys(paneIds[j], pane.Command, "Enter")
+			}
+		}
+	}
+
+	if !HasSession(sessionName) {
+		return fmt.Errorf("couldn't restore session %s", sessionName)
+	}
+	return nil
+}
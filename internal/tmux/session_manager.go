@@ -1,18 +1,36 @@
 package tmux
 
-func CreateSessionInDirectory(sessionName, directory string) error {
-	NewSessionWithDirectory(sessionName, directory)
+import (
+	"fmt"
+
+	"github.com/j-clemons/twt/internal/command"
+)
+
+// CreateSessionInDirectory creates a detached session starting in directory, with env vars
+// given as KEY=value set for every pane of it.
+func CreateSessionInDirectory(sessionName, directory string, env ...string) error {
+	NewSessionWithDirectory(sessionName, directory, env...)
 	return nil
 }
 
-func SetupWorktreeSession(sessionName, baseDir, worktreeName string) error {
-	SendKeys(sessionName, "clear", "Enter")
+// CreateSessionWithWindow creates a detached session whose first window is named windowName
+// and starts in directory.
+func CreateSessionWithWindow(sessionName, windowName, directory string) error {
+	if _, stderr := command.Run("tmux", "new-session", "-d", "-s", sessionName, "-n", windowName, "-c", directory); len(stderr) > 0 {
+		return fmt.Errorf("couldn't create session %s: %v", sessionName, stderr)
+	}
 	return nil
 }
 
-func FinalizeSession(sessionName, currentSession string, removeCurrentSession bool) {
-	SwitchToSession(sessionName)
-	if removeCurrentSession {
-		KillSession(currentSession)
+// NewWindow adds a window named windowName to the session, starting in directory.
+func NewWindow(sessionName, windowName, directory string) error {
+	if _, stderr := command.Run("tmux", "new-window", "-d", "-t", sessionName+":", "-n", windowName, "-c", directory); len(stderr) > 0 {
+		return fmt.Errorf("couldn't create window %s: %v", windowName, stderr)
 	}
+	return nil
+}
+
+func SetupWorktreeSession(sessionName, baseDir, worktreeName string) error {
+	SendKeys(sessionName, "clear", "Enter")
+	return nil
 }
@@ -2,6 +2,12 @@ package tmux
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/j-clemons/twt/internal/command"
 )
@@ -10,31 +16,83 @@ func SwitchToSession(name string) {
 	command.Run("tmux", "switch", "-t", name)
 }
 
+// InsideTmux reports whether twt is running in a tmux client, so there's a client to switch.
+func InsideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// AttachOrSwitch switches the tmux client to the session. Outside tmux, where there's no
+// client to switch, it replaces twt with `tmux attach-session` instead, so it only returns
+// on error.
+func AttachOrSwitch(name string) error {
+	if InsideTmux() {
+		SwitchToSession(name)
+		return nil
+	}
+
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		return fmt.Errorf("couldn't find tmux: %w", err)
+	}
+	return syscall.Exec(tmuxPath, []string{"tmux", "attach-session", "-t", name}, os.Environ())
+}
+
+// SwitchDescription describes what AttachOrSwitch will do, for plans.
+func SwitchDescription(name string) string {
+	if InsideTmux() {
+		return fmt.Sprintf("switch to %s", name)
+	}
+	return fmt.Sprintf("attach to %s", name)
+}
+
 func NewSession(cleanBranchName string) {
 	command.Run("tmux", "new-session", "-s", cleanBranchName, "-d")
 }
 
-func NewSessionWithDirectory(sessionName, startingDir string) {
-	command.Run("tmux", "new-session", "-s", sessionName, "-c", startingDir, "-d")
+func NewSessionWithDirectory(sessionName, startingDir string, env ...string) {
+	args := []string{"new-session", "-s", sessionName, "-c", startingDir, "-d"}
+	command.Run("tmux", append(args, envArgs(env)...)...)
+}
+
+// envArgs sets env vars given as KEY=value for a new session.
+func envArgs(env []string) []string {
+	var args []string
+	for _, kv := range env {
+		args = append(args, "-e", kv)
+	}
+	return args
 }
 
 func KillSession(name string) {
 	command.Run("tmux", "kill-session", "-t", name)
 }
 
+// GetCurrentSessionName returns the session of the client twt is running in. Outside tmux
+// there's none, rather than whichever session tmux would pick.
 func GetCurrentSessionName() (string, error) {
+	if !InsideTmux() {
+		return "", errors.New("Not inside a tmux session")
+	}
 	out, _ := command.Run("tmux", "display-message", "-p", "#S")
 	if len(out) == 0 {
 		return "", errors.New("Couldn't fetch current tmux session name")
 	}
 	return out[0], nil
+}
 
+// GetPreviousSessionName returns the session the current client was on before this one.
+func GetPreviousSessionName() (string, error) {
+	out, _ := command.Run("tmux", "display-message", "-p", "#{client_last_session}")
+	if len(out) == 0 || out[0] == "" {
+		return "", errors.New("Couldn't fetch previous tmux session name")
+	}
+	return out[0], nil
 }
 
 func ListSessions(justNames bool) ([]string, error) {
 	args := []string{"list-sessions"}
 	if justNames {
-		fetchNameOpts := []string{"-F", "\"#{session_name}\""}
+		fetchNameOpts := []string{"-F", "#{session_name}"}
 		args = append(args, fetchNameOpts...)
 	}
 	out, _ := command.Run("tmux", args...)
@@ -50,3 +108,72 @@ func HasSession(name string) bool {
 	return len(stderr) == 0
 
 }
+
+func CapturePane(sessionName string) ([]string, error) {
+	out, stderr := command.Run("tmux", "capture-pane", "-p", "-t", sessionName)
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("couldn't capture pane for session %s: %v", sessionName, stderr)
+	}
+	return out, nil
+}
+
+// ListAttachedClients maps each running session to the number of clients attached to it.
+// Returns an empty map when no tmux server is running.
+func ListAttachedClients() map[string]int {
+	out, _ := command.Run("tmux", "list-sessions", "-F", "#{session_name}\t#{session_attached}")
+
+	clients := make(map[string]int, len(out))
+	for _, line := range out {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		attached, err := strconv.Atoi(parts[1])
+		if err != nil {
+			attached = 0
+		}
+		clients[parts[0]] = attached
+	}
+	return clients
+}
+
+// ListPaneDirectories maps each running session to the working directories of its panes.
+func ListPaneDirectories() map[string][]string {
+	out, _ := command.Run("tmux", "list-panes", "-a", "-F", "#{session_name}\t#{pane_current_path}")
+
+	dirs := make(map[string][]string)
+	for _, line := range out {
+		name, dir, ok := strings.Cut(line, "\t")
+		if ok {
+			dirs[name] = append(dirs[name], dir)
+		}
+	}
+	return dirs
+}
+
+type PaneProcess struct {
+	ID  string
+	PID int
+}
+
+// ListPaneProcesses returns the id and shell pid of every pane in the session.
+func ListPaneProcesses(sessionName string) ([]PaneProcess, error) {
+	out, stderr := command.Run("tmux", "list-panes", "-s", "-t", sessionName, "-F", "#{pane_id}\t#{pane_pid}")
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("couldn't list panes for session %s: %v", sessionName, stderr)
+	}
+
+	var panes []PaneProcess
+	for _, line := range out {
+		id, pid, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		pidNum, err := strconv.Atoi(pid)
+		if err != nil {
+			continue
+		}
+		panes = append(panes, PaneProcess{ID: id, PID: pidNum})
+	}
+	return panes, nil
+}
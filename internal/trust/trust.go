@@ -0,0 +1,282 @@
+package trust
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/fsutil"
+	"github.com/j-clemons/twt/internal/git"
+)
+
+const StoreFileName = "trust.json"
+
+// Entry is a script the user approved, with the content they approved so a later change
+// can be shown as a diff.
+type Entry struct {
+	Path      string    `json:"path"`
+	Hash      string    `json:"hash"`
+	Content   string    `json:"content"`
+	AllowedAt time.Time `json:"allowed_at"`
+}
+
+type store struct {
+	Scripts map[string]Entry `json:"scripts"`
+}
+
+type Status string
+
+const (
+	Trusted Status = "trusted"
+	// Never approved
+	New Status = "new"
+	// Approved, but the content changed since
+	Changed Status = "changed"
+	// Approved, but the file is gone
+	Missing Status = "missing"
+)
+
+// Hash returns the hash scripts are trusted by.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Check returns whether the script at path is trusted as it is now, and the entry for it if
+// it was ever approved.
+func Check(path string) (Status, Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", Entry{}, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		var s store
+		if err := read(&s); err != nil {
+			return "", Entry{}, err
+		}
+		if entry, approved := s.Scripts[path]; approved {
+			return Missing, entry, nil
+		}
+	}
+	if err != nil {
+		return "", Entry{}, err
+	}
+	return checkContent(path, content)
+}
+
+// checkContent returns whether content, read from the script at path, is what was trusted.
+func checkContent(path string, content []byte) (Status, Entry, error) {
+	var s store
+	if err := read(&s); err != nil {
+		return "", Entry{}, err
+	}
+	entry, approved := s.Scripts[path]
+	switch {
+	case !approved:
+		return New, entry, nil
+	case entry.Hash != Hash(content):
+		return Changed, entry, nil
+	}
+	return Trusted, entry, nil
+}
+
+// Allow trusts the script at path as it is now.
+func Allow(path string) (Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	return allow(path, content)
+}
+
+// allow trusts content as the script at path.
+func allow(path string, content []byte) (Entry, error) {
+	entry := Entry{Path: path, Hash: Hash(content), Content: string(content), AllowedAt: time.Now()}
+	return entry, update(func(s *store) bool {
+		s.Scripts[path] = entry
+		return true
+	})
+}
+
+// Revoke stops trusting the script at path, which needn't exist any more.
+func Revoke(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	found := false
+	err = update(func(s *store) bool {
+		_, found = s.Scripts[path]
+		delete(s.Scripts, path)
+		return found
+	})
+	if err == nil && !found {
+		return fmt.Errorf("%s isn't trusted", path)
+	}
+	return err
+}
+
+// IsTrusted reports whether the script at path is trusted as it is now.
+func IsTrusted(path string) bool {
+	status, _, err := Check(path)
+	return err == nil && status == Trusted
+}
+
+// List returns the approved scripts sorted by path.
+func List() ([]Entry, error) {
+	var s store
+	if err := read(&s); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(s.Scripts))
+	for _, entry := range s.Scripts {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// Diff renders what changed in the script at path since it was approved, or all of it if
+// it never was.
+func Diff(path string, entry Entry) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return git.DiffContents(path, []byte(entry.Content), content)
+}
+
+// Approve returns the content of the script at path if it's trusted. Otherwise it shows
+// what's new or changed and asks whether to trust it, refusing when there's no terminal to
+// ask on. name is what the script is called in messages. The file is read once, and what's
+// returned is exactly what was checked, so run that rather than the file, which may have
+// changed since.
+func Approve(name, path string) ([]byte, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", name, err)
+	}
+	status, entry, err := checkContent(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't check whether %s is trusted: %w", name, err)
+	}
+	if status == Trusted {
+		return content, nil
+	}
+	if !interactive() {
+		return nil, fmt.Errorf("%s is %s and not trusted, and there's no terminal to ask on - review it and run 'twt trust allow %s'", name, status, path)
+	}
+
+	diff, err := git.DiffContents(path, []byte(entry.Content), content)
+	if err != nil {
+		return nil, err
+	}
+	if status == New {
+		fmt.Printf("%s hasn't been run before:\n\n%s\n", name, diff)
+	} else {
+		fmt.Printf("%s changed since you trusted it:\n\n%s\n", name, diff)
+	}
+	fmt.Printf("Trust and run %s? (y/N): ", name)
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if err != nil || (response != "y" && response != "yes") {
+		return nil, fmt.Errorf("%s isn't trusted", name)
+	}
+
+	if _, err := allow(path, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// interactive reports whether stdin is a terminal the user can answer on.
+func interactive() bool {
+	return isatty.IsTerminal(os.Stdin.Fd())
+}
+
+func storePath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, StoreFileName), nil
+}
+
+func read(s *store) error {
+	path, err := storePath()
+	if err != nil {
+		return err
+	}
+	return fsutil.WithLock(path, 0600, syscall.LOCK_SH, func() error {
+		return readFile(path, s)
+	})
+}
+
+func readFile(path string, s *store) error {
+	s.Scripts = make(map[string]Entry)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("failed to parse trust store: %w", err)
+	}
+	if s.Scripts == nil {
+		s.Scripts = make(map[string]Entry)
+	}
+	return nil
+}
+
+// update changes the store under an exclusive lock, writing it if fn reports a change.
+func update(fn func(s *store) bool) error {
+	path, err := storePath()
+	if err != nil {
+		return err
+	}
+	return fsutil.WithLock(path, 0600, syscall.LOCK_EX, func() error {
+		var s store
+		if err := readFile(path, &s); err != nil {
+			return err
+		}
+		if !fn(&s) {
+			return nil
+		}
+		return writeFile(path, s)
+	})
+}
+
+// writeFile replaces the store in one go, so a crash never leaves half of it.
+func writeFile(path string, s store) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0600)
+}
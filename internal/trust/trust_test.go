@@ -0,0 +1,108 @@
+package trust_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/trust"
+)
+
+func TestTrust(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	script := filepath.Join(dir, "post.sh")
+	write := func(content string) {
+		if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectStatus := func(name string, expected trust.Status) {
+		status, _, err := trust.Check(script)
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", name, err)
+		}
+		if status != expected {
+			t.Fatalf("%s: Expected %s but got %s", name, expected, status)
+		}
+	}
+
+	write("#!/bin/sh\necho one\n")
+	expectStatus("Never approved", trust.New)
+
+	if _, err := trust.Allow(script); err != nil {
+		t.Fatalf("Allow: Expected success but got error: %s", err)
+	}
+	expectStatus("Approved", trust.Trusted)
+
+	write("#!/bin/sh\necho two\n")
+	expectStatus("Changed after approval", trust.Changed)
+	_, entry, _ := trust.Check(script)
+	if entry.Content != "#!/bin/sh\necho one\n" {
+		t.Fatalf("Changed after approval: Expected the approved content to be kept but got %q", entry.Content)
+	}
+
+	write("#!/bin/sh\necho one\n")
+	expectStatus("Changed back", trust.Trusted)
+
+	if err := os.Remove(script); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus("Removed", trust.Missing)
+
+	if err := trust.Revoke(script); err != nil {
+		t.Fatalf("Revoke: Expected success but got error: %s", err)
+	}
+	if err := trust.Revoke(script); err == nil {
+		t.Fatalf("Revoke twice: Expected error but got success")
+	}
+	if entries, _ := trust.List(); len(entries) != 0 {
+		t.Fatalf("Revoke: Expected no trusted scripts but got %d", len(entries))
+	}
+}
+
+func TestApproveRefusesChangedScript(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	// Without a terminal to ask on, anything untrusted is refused
+	stdin := os.Stdin
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = devNull
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		devNull.Close()
+	})
+
+	script := filepath.Join(dir, "post.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho one\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trust.Approve("post.sh", script); err == nil {
+		t.Fatalf("Never approved: Expected it to be refused")
+	}
+
+	if _, err := trust.Allow(script); err != nil {
+		t.Fatalf("Allow: Expected success but got error: %s", err)
+	}
+	content, err := trust.Approve("post.sh", script)
+	if err != nil {
+		t.Fatalf("Approved: Expected success but got error: %s", err)
+	}
+	if string(content) != "#!/bin/sh\necho one\n" {
+		t.Fatalf("Approved: Expected the approved content but got %q", content)
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho two\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := trust.Approve("post.sh", script); err == nil {
+		t.Fatalf("Changed after approval: Expected it to be refused but got %q", content)
+	}
+}
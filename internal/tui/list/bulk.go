@@ -0,0 +1,274 @@
+package list
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+type viewMode int
+
+const (
+	modeList viewMode = iota
+	modeConfirm
+	modeProgress
+)
+
+type bulkResult struct {
+	done bool
+	err  error
+}
+
+type bulkOp struct {
+	mode           workflow.RemoveMode
+	force          bool
+	targets        []state.SessionInfo
+	warnings       map[string][]string
+	warningsLoaded bool
+	results        []bulkResult
+	current        int
+	finished       bool
+	// Session the TUI was started from, removed last after switching away
+	currentSession string
+}
+
+type warningsLoadedMsg struct {
+	warnings map[string][]string
+}
+
+type bulkStepMsg struct {
+	index int
+	err   error
+}
+
+// isMarked reports whether the row is marked, either directly or by the visual range.
+func (m model) isMarked(i int) bool {
+	if m.marked[m.sessions[i].Name] {
+		return true
+	}
+	if !m.visual {
+		return false
+	}
+	return i >= min(m.anchor, m.cursor) && i <= max(m.anchor, m.cursor)
+}
+
+// commitVisual marks every row in the visual range and leaves visual mode.
+func (m *model) commitVisual() {
+	for i := min(m.anchor, m.cursor); i <= max(m.anchor, m.cursor) && i < len(m.sessions); i++ {
+		m.marked[m.sessions[i].Name] = true
+	}
+	m.visual = false
+}
+
+func (m model) markedCount() int {
+	count := 0
+	for i := range m.sessions {
+		if m.isMarked(i) {
+			count++
+		}
+	}
+	return count
+}
+
+// bulkTargets returns the marked sessions in list order, or the highlighted one if none are.
+func (m model) bulkTargets() []state.SessionInfo {
+	var targets []state.SessionInfo
+	for i, session := range m.sessions {
+		if m.isMarked(i) {
+			targets = append(targets, session)
+		}
+	}
+	if len(targets) == 0 && len(m.sessions) > 0 {
+		targets = append(targets, m.sessions[m.cursor])
+	}
+	return targets
+}
+
+func (m *model) startBulk(mode workflow.RemoveMode) tea.Cmd {
+	targets := m.bulkTargets()
+	if len(targets) == 0 {
+		return nil
+	}
+	if m.visual {
+		m.commitVisual()
+	}
+
+	m.mode = modeConfirm
+	m.bulk = &bulkOp{
+		mode:    mode,
+		targets: targets,
+		results: make([]bulkResult, len(targets)),
+	}
+	return loadWarnings(targets, mode)
+}
+
+func loadWarnings(targets []state.SessionInfo, mode workflow.RemoveMode) tea.Cmd {
+	return func() tea.Msg {
+		warnings := make(map[string][]string)
+		if mode == workflow.KillSessionOnly {
+			return warningsLoadedMsg{warnings: warnings}
+		}
+
+		for _, session := range targets {
+			if session.IsCommon() {
+				continue
+			}
+			if git.IsDirty(session.WorktreePath) {
+				warnings[session.Name] = append(warnings[session.Name], "uncommitted changes")
+			}
+			unpushed, err := git.UnpushedCount(session.WorktreePath)
+			if err == nil && unpushed > 0 {
+				warnings[session.Name] = append(warnings[session.Name], fmt.Sprintf("%d unpushed commit(s)", unpushed))
+			}
+		}
+		return warningsLoadedMsg{warnings: warnings}
+	}
+}
+
+// runBulk orders the targets so the current session goes last, since removing it switches
+// the client away, then starts the first step.
+func (m *model) runBulk() tea.Cmd {
+	currentSession, _ := tmux.GetCurrentSessionName()
+
+	ordered := make([]state.SessionInfo, 0, len(m.bulk.targets))
+	var current []state.SessionInfo
+	for _, session := range m.bulk.targets {
+		if session.Name == currentSession {
+			current = append(current, session)
+		} else {
+			ordered = append(ordered, session)
+		}
+	}
+	m.bulk.targets = append(ordered, current...)
+	m.bulk.currentSession = currentSession
+	m.mode = modeProgress
+
+	return m.bulkStep(0)
+}
+
+func (m model) bulkStep(index int) tea.Cmd {
+	session := m.bulk.targets[index]
+	mode := m.bulk.mode
+	opts := workflow.RemoveOptions{
+		Force:          m.bulk.force,
+		Archive:        m.bulk.force && m.archiveOnForce,
+		CurrentSession: m.bulk.currentSession,
+		Destinations:   m.destinations,
+	}
+
+	return func() tea.Msg {
+		return bulkStepMsg{index: index, err: workflow.RemoveSession(session, mode, opts)}
+	}
+}
+
+func (m model) updateBulk(msg tea.Msg) (model, tea.Cmd) {
+	if m.bulk == nil {
+		return m, nil
+	}
+
+	switch msg := msg.(type) {
+
+	case warningsLoadedMsg:
+		m.bulk.warnings = msg.warnings
+		m.bulk.warningsLoaded = true
+
+	case bulkStepMsg:
+		m.bulk.results[msg.index] = bulkResult{done: true, err: msg.err}
+		if msg.index+1 < len(m.bulk.targets) {
+			m.bulk.current = msg.index + 1
+			return m, m.bulkStep(msg.index + 1)
+		}
+		m.bulk.finished = true
+
+	case tea.KeyMsg:
+		key := msg.String()
+		if key == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		if m.mode == modeConfirm {
+			switch {
+			case config.Matches(key, m.keys.Confirm):
+				// Not before the user has seen what would be lost
+				if m.bulk.warningsLoaded {
+					return m, m.runBulk()
+				}
+			case config.Matches(key, m.keys.ToggleForce):
+				m.bulk.force = !m.bulk.force
+			case config.Matches(key, m.keys.Decline) || config.Matches(key, m.keys.Cancel) || config.Matches(key, m.keys.Quit):
+				m.mode = modeList
+				m.bulk = nil
+			}
+			return m, nil
+		}
+
+		if m.bulk.finished {
+			m.mode = modeList
+			m.bulk = nil
+			m.marked = make(map[string]bool)
+			load := m.refresh()
+			return m, load
+		}
+	}
+
+	return m, nil
+}
+
+func (m model) renderConfirm() string {
+	var s strings.Builder
+
+	s.WriteString(m.styles.header.Render(fmt.Sprintf("About to %s for %d session(s):", m.bulk.mode, len(m.bulk.targets))) + "\n\n")
+	for _, session := range m.bulk.targets {
+		s.WriteString(fmt.Sprintf("  %s (%s)\n", branchLabel(session), repoName(session.RepoName, session.RepoPath)))
+		for _, warning := range m.bulk.warnings[session.Name] {
+			s.WriteString(m.styles.warning.Render(fmt.Sprintf("    ! %s", warning)) + "\n")
+		}
+	}
+
+	if !m.bulk.warningsLoaded {
+		s.WriteString("\n" + m.styles.muted.Render("Checking for uncommitted and unpushed work...") + "\n")
+	}
+	if m.bulk.force && m.archiveOnForce && m.bulk.mode != workflow.KillSessionOnly {
+		s.WriteString("\n" + m.styles.warning.Render("Force is on: uncommitted changes will be archived, use 'twt restore' to bring them back.") + "\n")
+	} else if m.bulk.force {
+		s.WriteString("\n" + m.styles.warning.Render("Force is on: uncommitted changes and unmerged branches will be lost.") + "\n")
+	}
+
+	confirm := fmt.Sprintf("%s confirm • ", keyLabel(m.keys.Confirm))
+	if !m.bulk.warningsLoaded {
+		confirm = "checking… • "
+	}
+	s.WriteString("\n" + m.styles.muted.Render(fmt.Sprintf("%s%s toggle force • %s cancel", confirm, keyLabel(m.keys.ToggleForce), keyLabel(m.keys.Decline))))
+	return s.String()
+}
+
+func (m model) renderProgress() string {
+	var s strings.Builder
+
+	s.WriteString(m.styles.header.Render(fmt.Sprintf("Running %s:", m.bulk.mode)) + "\n\n")
+	for i, session := range m.bulk.targets {
+		result := m.bulk.results[i]
+		switch {
+		case result.done && result.err != nil:
+			s.WriteString(m.styles.warning.Render(fmt.Sprintf("  ✗ %s: %v", branchLabel(session), result.err)))
+		case result.done:
+			s.WriteString(fmt.Sprintf("  ✓ %s", branchLabel(session)))
+		case i == m.bulk.current:
+			s.WriteString(fmt.Sprintf("  … %s", branchLabel(session)))
+		default:
+			s.WriteString(m.styles.muted.Render(fmt.Sprintf("    %s", branchLabel(session))))
+		}
+		s.WriteString("\n")
+	}
+
+	if m.bulk.finished {
+		s.WriteString("\n" + m.styles.muted.Render("Press any key to return to the list"))
+	}
+	return s.String()
+}
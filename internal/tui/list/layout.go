@@ -0,0 +1,106 @@
+package list
+
+import (
+	"path/filepath"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	cursorWidth  = 2
+	createdWidth = 8
+	statusWidth  = 14
+	minRepoWidth = 10
+	// Column widths used before the terminal size is known
+	defaultRepoWidth   = 15
+	defaultBranchWidth = 25
+	// Title, blank line, column headers and the rule under them
+	headerLines = 4
+	// Blank line and help line
+	footerLines = 2
+	minListRows = 3
+)
+
+type columns struct {
+	repo   int
+	branch int
+}
+
+// listWidth is the width available to the session list, excluding any side preview.
+func (m model) listWidth() int {
+	if m.showPreview && m.sidePreview() {
+		return m.width / 2
+	}
+	return m.width
+}
+
+// columnWidths splits the list width between the repo and branch columns. The repo column
+// only grows as wide as the longest repo name, leaving the rest for branches.
+func (m model) columnWidths() columns {
+	width := m.listWidth()
+	if width == 0 {
+		return columns{repo: defaultRepoWidth, branch: defaultBranchWidth}
+	}
+
+	// Separators between the four columns
+	available := width - cursorWidth - createdWidth - statusWidth - 3
+	longestRepo := len("REPOSITORY")
+	for _, session := range m.sessions {
+		longestRepo = max(longestRepo, runewidth.StringWidth(repoName(session.RepoName, session.RepoPath)))
+	}
+
+	repo := min(longestRepo, max(minRepoWidth, available/3))
+	branch := max(available-repo, minRepoWidth)
+	return columns{repo: repo, branch: branch}
+}
+
+// listRows is the number of session rows that fit on screen. Zero means no limit.
+func (m model) listRows() int {
+	if m.height == 0 {
+		return 0
+	}
+
+	extra := 0
+	if m.refreshErr != nil {
+		extra++
+	}
+	if m.actionErr != nil {
+		extra++
+	}
+	if m.restoring != "" {
+		extra++
+	}
+	rows := m.height - headerLines - footerLines - extra
+	if m.showPreview && !m.sidePreview() {
+		rows /= 2
+	}
+	return max(rows, minListRows)
+}
+
+// scrollToCursor moves the viewport so the cursor stays visible.
+func (m *model) scrollToCursor() {
+	rows := m.listRows()
+	if rows == 0 {
+		m.offset = 0
+		return
+	}
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.sessions)-rows))
+}
+
+func repoName(name, path string) string {
+	if name == "" {
+		return filepath.Base(path)
+	}
+	return name
+}
+
+// fit truncates s with an ellipsis and pads it to exactly width cells.
+func fit(s string, width int) string {
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
@@ -0,0 +1,166 @@
+package list
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+const (
+	previewCommitCount = 5
+	previewPaneLines   = 10
+	// Below this terminal width the preview is drawn under the list instead of beside it.
+	sidePreviewMinWidth = 120
+)
+
+type preview struct {
+	commits []string
+	status  []string
+	pane    []string
+	err     error
+}
+
+type previewLoadedMsg struct {
+	sessionName string
+	preview     preview
+}
+
+func loadPreview(session state.SessionInfo) tea.Cmd {
+	return func() tea.Msg {
+		var p preview
+
+		commits, err := git.RecentCommits(session.WorktreePath, previewCommitCount)
+		if err != nil {
+			p.err = err
+		}
+		p.commits = commits
+
+		status, err := git.StatusSummary(session.WorktreePath)
+		if err != nil && p.err == nil {
+			p.err = err
+		}
+		p.status = status
+
+		if session.IsActive() {
+			pane, err := tmux.CapturePane(session.Name)
+			if err != nil && p.err == nil {
+				p.err = err
+			}
+			p.pane = lastLines(trimTrailingBlank(pane), previewPaneLines)
+		}
+
+		return previewLoadedMsg{sessionName: session.Name, preview: p}
+	}
+}
+
+// requestPreview returns a command loading the preview for the highlighted session, unless
+// the preview is hidden or already loaded.
+func (m *model) requestPreview() tea.Cmd {
+	if !m.showPreview || len(m.sessions) == 0 {
+		return nil
+	}
+	if _, ok := m.previews[m.sessions[m.cursor].Name]; ok {
+		return nil
+	}
+	return m.reloadPreview()
+}
+
+// reloadPreview returns a command loading the preview for the highlighted session even if
+// it's cached, so its pane and git status keep up with the session. The cached one is shown
+// until then.
+func (m *model) reloadPreview() tea.Cmd {
+	if !m.showPreview || len(m.sessions) == 0 {
+		return nil
+	}
+	return loadPreview(m.sessions[m.cursor])
+}
+
+func (m model) renderPreview() string {
+	if len(m.sessions) == 0 {
+		return ""
+	}
+	session := m.sessions[m.cursor]
+
+	headerStyle := m.styles.header
+	var s strings.Builder
+
+	s.WriteString(headerStyle.Render("Path") + "\n")
+	s.WriteString(session.WorktreePath + "\n\n")
+
+	p, ok := m.previews[session.Name]
+	if !ok {
+		s.WriteString("Loading preview...\n")
+		return s.String()
+	}
+	if p.err != nil {
+		s.WriteString(fmt.Sprintf("Error: %v\n\n", p.err))
+	}
+
+	s.WriteString(headerStyle.Render("Recent commits") + "\n")
+	writeLines(&s, p.commits, "No commits")
+
+	s.WriteString("\n" + headerStyle.Render("Status") + "\n")
+	writeLines(&s, p.status, "Clean")
+
+	s.WriteString("\n" + headerStyle.Render("Active pane") + "\n")
+	if !session.IsActive() {
+		s.WriteString("Session not running\n")
+	} else {
+		writeLines(&s, p.pane, "Empty")
+	}
+
+	return s.String()
+}
+
+func (m model) previewStyle() lipgloss.Style {
+	style := m.styles.border
+	if m.sidePreview() {
+		style = style.MarginLeft(2).Width(m.width - m.listWidth() - 4)
+		if m.height > 0 {
+			style = style.MaxHeight(m.height)
+		}
+		return style
+	}
+	if m.width > 0 {
+		style = style.Width(m.width - 2)
+	}
+	if m.height > 0 {
+		listHeight := headerLines + m.listRows() + footerLines
+		style = style.MaxHeight(max(m.height-listHeight, minListRows))
+	}
+	return style
+}
+
+func (m model) sidePreview() bool {
+	return m.width >= sidePreviewMinWidth
+}
+
+func writeLines(s *strings.Builder, lines []string, empty string) {
+	if len(lines) == 0 {
+		s.WriteString(empty + "\n")
+		return
+	}
+	for _, line := range lines {
+		s.WriteString(line + "\n")
+	}
+}
+
+func trimTrailingBlank(lines []string) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[:end]
+}
+
+func lastLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
@@ -0,0 +1,94 @@
+package list
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+const refreshInterval = 2 * time.Second
+
+// Loader fetches the sessions to display. It's called on every refresh so sessions created
+// or killed elsewhere show up without restarting the list.
+type Loader func() ([]state.SessionInfo, error)
+
+type refreshTickMsg struct{}
+
+type sessionsLoadedMsg struct {
+	sessions []state.SessionInfo
+	err      error
+}
+
+func scheduleRefresh() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+// refresh starts loading the sessions, noting it's underway so ticks don't pile more loads
+// on a slow one.
+func (m *model) refresh() tea.Cmd {
+	m.refreshing = true
+	return loadSessions(m.load)
+}
+
+func loadSessions(load Loader) tea.Cmd {
+	return func() tea.Msg {
+		sessions, err := load()
+		if err != nil {
+			return sessionsLoadedMsg{err: err}
+		}
+		state.DetectDirty(sessions)
+		return sessionsLoadedMsg{sessions: sessions}
+	}
+}
+
+// applySessions swaps in freshly loaded sessions, keeping the cursor on the same session
+// where it still exists. Cached previews of other rows are kept unless their session is gone
+// or changed; the highlighted row's is reloaded on every refresh, see reloadPreview.
+func (m *model) applySessions(sessions []state.SessionInfo) {
+	previous := make(map[string]state.SessionInfo, len(m.sessions))
+	for _, session := range m.sessions {
+		previous[session.Name] = session
+	}
+
+	var selected string
+	if m.cursor < len(m.sessions) {
+		selected = m.sessions[m.cursor].Name
+	}
+
+	if m.sortRecent {
+		state.SortByRecent(sessions)
+	} else {
+		state.SortByCreated(sessions)
+	}
+
+	m.sessions = sessions
+	m.cursor = 0
+	for i, session := range sessions {
+		if session.Name == selected {
+			m.cursor = i
+			break
+		}
+	}
+
+	current := make(map[string]state.SessionInfo, len(sessions))
+	for _, session := range sessions {
+		current[session.Name] = session
+	}
+	for name := range m.previews {
+		if !samePreview(previous[name], current[name]) {
+			delete(m.previews, name)
+		}
+	}
+}
+
+// samePreview reports whether a session's cached preview still holds: it's still listed, in
+// the same worktree, with the same status.
+func samePreview(before, after state.SessionInfo) bool {
+	return after.Name != "" &&
+		before.WorktreePath == after.WorktreePath &&
+		before.Status == after.Status &&
+		before.Dirty == after.Dirty
+}
@@ -0,0 +1,32 @@
+package list
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/j-clemons/twt/internal/config"
+)
+
+type styles struct {
+	highlight lipgloss.Style
+	header    lipgloss.Style
+	muted     lipgloss.Style
+	warning   lipgloss.Style
+	hint      lipgloss.Style
+	border    lipgloss.Style
+}
+
+func newStyles(theme config.Theme) styles {
+	return styles{
+		highlight: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.HighlightBackground)).
+			Foreground(lipgloss.Color(theme.HighlightForeground)).
+			Bold(true),
+		header:  lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Header)).Bold(true),
+		muted:   lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)),
+		warning: lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Warning)),
+		hint:    lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Hint)),
+		border: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color(theme.Border)).
+			Padding(0, 1),
+	}
+}
@@ -2,117 +2,405 @@ package list
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/fatih/color"
+	"github.com/j-clemons/twt/internal/config"
 	"github.com/j-clemons/twt/internal/state"
-	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+	"github.com/j-clemons/twt/internal/workflow"
 )
 
 type model struct {
-	sessions []state.SessionInfo
-	cursor   int
+	load        Loader
+	sessions    []state.SessionInfo
+	cursor      int
+	offset      int
+	width       int
+	height      int
+	showPreview bool
+	sortRecent  bool
+	previews    map[string]preview
+	// A load of the sessions is underway
+	refreshing bool
+	refreshErr error
+	actionErr  error
+	// Session being restored before switching to it
+	restoring string
+	keys      config.KeyMap
+	styles    styles
+	// Where to go when the current session is removed
+	destinations []string
+	// Whether forced removals archive uncommitted work first
+	archiveOnForce bool
+	// Bulk operations
+	mode   viewMode
+	marked map[string]bool
+	visual bool
+	anchor int
+	bulk   *bulkOp
+	// Session picked to switch to, once the TUI has exited
+	selected string
 }
 
-func CreateModel(sessions []state.SessionInfo) model {
-	return model{
-		sessions: sessions,
+func CreateModel(load Loader, sessions []state.SessionInfo, cfg *config.Config) model {
+	m := model{
+		load:       load,
+		previews:   make(map[string]preview),
+		marked:     make(map[string]bool),
+		keys:       cfg.Keys,
+		styles:     newStyles(cfg.Theme),
+		sortRecent: cfg.List.Sort == config.SortRecent,
+
+		destinations:   cfg.Remove.Destinations,
+		archiveOnForce: cfg.Remove.ArchiveOnForce,
 	}
+	m.applySessions(sessions)
+	// Init starts loading
+	m.refreshing = true
+	return m
 }
 
-func Create(sessions []state.SessionInfo) tea.Program {
-	return *tea.NewProgram(CreateModel(sessions))
+func Create(load Loader, sessions []state.SessionInfo, cfg *config.Config) tea.Program {
+	return *tea.NewProgram(CreateModel(load, sessions, cfg))
+}
+
+// Selected returns the session picked in the finished TUI, if any. Switching, or attaching
+// from outside tmux, is left until the TUI has given the terminal back.
+func Selected(final tea.Model) string {
+	if m, ok := final.(model); ok {
+		return m.selected
+	}
+	return ""
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(loadSessions(m.load), scheduleRefresh())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.update(msg)
+	m.scrollToCursor()
+	return m, cmd
+}
+
+func (m model) update(msg tea.Msg) (model, tea.Cmd) {
 	switch msg := msg.(type) {
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case previewLoadedMsg:
+		m.previews[msg.sessionName] = msg.preview
+
+	case refreshTickMsg:
+		if m.refreshing {
+			return m, scheduleRefresh()
+		}
+		load := m.refresh()
+		return m, tea.Batch(load, scheduleRefresh())
+
+	case sessionsLoadedMsg:
+		m.refreshing = false
+		m.refreshErr = msg.err
+		if msg.err == nil {
+			m.applySessions(msg.sessions)
+		}
+		return m, m.reloadPreview()
+
+	case warningsLoadedMsg, bulkStepMsg:
+		return m.updateBulk(msg)
+
+	case sessionRestoredMsg:
+		m.restoring = ""
+		if msg.err != nil {
+			m.actionErr = fmt.Errorf("couldn't restore %s: %w", msg.sessionName, msg.err)
+			return m, nil
+		}
+		return m.selectSession(msg.sessionName)
+
 	case tea.KeyMsg:
+		if m.mode != modeList {
+			return m.updateBulk(msg)
+		}
+		// Only quitting until the restore is done
+		if m.restoring != "" && msg.String() != "ctrl+c" && !config.Matches(msg.String(), m.keys.Quit) {
+			return m, nil
+		}
+
+		m.actionErr = nil
+		key := msg.String()
+		page := max(m.listRows(), 1)
+		last := max(len(m.sessions)-1, 0)
 
-		switch msg.String() {
+		switch {
 
-		case "ctrl+c", "q":
+		case key == "ctrl+c", config.Matches(key, m.keys.Quit):
 			return m, tea.Quit
 
-		case "up", "k":
-			if m.cursor > 0 {
-				m.cursor--
+		case config.Matches(key, m.keys.Up):
+			m.cursor = max(m.cursor-1, 0)
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Down):
+			m.cursor = min(m.cursor+1, last)
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.PageUp):
+			m.cursor = max(m.cursor-page, 0)
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.PageDown):
+			m.cursor = min(m.cursor+page, last)
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Top):
+			m.cursor = 0
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Bottom):
+			m.cursor = last
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Preview):
+			m.showPreview = !m.showPreview
+			if m.showPreview {
+				// Reload on every toggle so the preview doesn't go stale
+				m.previews = make(map[string]preview)
+			}
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Sort):
+			m.sortRecent = !m.sortRecent
+			m.applySessions(m.sessions)
+			return m, m.requestPreview()
+
+		case config.Matches(key, m.keys.Mark):
+			if len(m.sessions) == 0 {
+				return m, nil
 			}
+			name := m.sessions[m.cursor].Name
+			m.marked[name] = !m.marked[name]
+			m.cursor = min(m.cursor+1, last)
+			return m, m.requestPreview()
 
-		case "down", "j":
-			if m.cursor < len(m.sessions)-1 {
-				m.cursor++
+		case config.Matches(key, m.keys.Visual):
+			if m.visual {
+				m.commitVisual()
+			} else {
+				m.visual = true
+				m.anchor = m.cursor
 			}
 
-		case "enter", " ":
-			tmux.SwitchToSession(m.sessions[m.cursor].Name)
-			return m, tea.Quit
+		case config.Matches(key, m.keys.Cancel):
+			if m.visual {
+				m.visual = false
+			} else {
+				m.marked = make(map[string]bool)
+			}
+
+		case config.Matches(key, m.keys.KillSession):
+			return m, m.startBulk(workflow.KillSessionOnly)
+
+		case config.Matches(key, m.keys.RemoveWorktree):
+			return m, m.startBulk(workflow.RemoveSessionWorktree)
+
+		case config.Matches(key, m.keys.RemoveBranch):
+			return m, m.startBulk(workflow.RemoveSessionWorktreeAndBranch)
+
+		case config.Matches(key, m.keys.Select):
+			if len(m.sessions) == 0 {
+				return m, nil
+			}
+			session := m.sessions[m.cursor]
+			if !session.IsActive() {
+				m.restoring = session.Name
+				return m, restoreSession(session)
+			}
+			return m.selectSession(session.Name)
 		}
 	}
 
 	return m, nil
 }
 
+type sessionRestoredMsg struct {
+	sessionName string
+	err         error
+}
+
+// restoreSession restores an inactive session in the background, as recreating its layout
+// takes a while.
+func restoreSession(session state.SessionInfo) tea.Cmd {
+	return func() tea.Msg {
+		return sessionRestoredMsg{sessionName: session.Name, err: workflow.RestoreSession(session)}
+	}
+}
+
+// selectSession picks the session to switch to once the TUI has exited.
+func (m model) selectSession(sessionName string) (model, tea.Cmd) {
+	m.selected = sessionName
+	state.UpdateLastAccessed(sessionName)
+	return m, tea.Quit
+}
+
 func (m model) View() string {
+	switch m.mode {
+	case modeConfirm:
+		return m.renderConfirm()
+	case modeProgress:
+		return m.renderProgress()
+	}
+
 	if len(m.sessions) == 0 {
-		color.Yellow("No TWT sessions found.")
-		color.Cyan("Use 'twt go <branch>' to create a new session.")
-		return ""
+		return m.styles.warning.Render("No TWT sessions found.") + "\n" +
+			m.styles.hint.Render("Use 'twt go <branch>' to create a new session.") + "\n" +
+			m.styles.muted.Render(m.helpLine()) + "\n"
 	}
 
-	var s strings.Builder
+	list := m.renderList()
+	if !m.showPreview {
+		return list
+	}
 
-	highlightStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("#7D56F4")).
-		Foreground(lipgloss.Color("#FFFFFF")).
-		Bold(true)
+	previewBox := m.previewStyle().Render(m.renderPreview())
+	if m.sidePreview() {
+		return lipgloss.JoinHorizontal(lipgloss.Top, list, previewBox)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, list, previewBox)
+}
 
-	s.WriteString("TWT Sessions:\n\n")
+func (m model) renderList() string {
+	var s strings.Builder
+	cols := m.columnWidths()
 
-	s.WriteString(fmt.Sprintf("  %-15s %-25s %-10s\n", "REPOSITORY", "BRANCH", "CREATED"))
-	s.WriteString(strings.Repeat("-", 65) + "\n")
+	s.WriteString(m.styles.header.Render("TWT Sessions:") + "\n\n")
 
-	for i, session := range m.sessions {
-		repoName := session.RepoName
-		if repoName == "" {
-			repoName = filepath.Base(session.RepoPath)
-		}
+	header := fmt.Sprintf("  %s %s %s %s",
+		fit("REPOSITORY", cols.repo),
+		fit("BRANCH", cols.branch),
+		fit(m.ageLabel(), createdWidth),
+		fit("STATUS", statusWidth),
+	)
+	s.WriteString(m.styles.header.Render(header) + "\n")
+	s.WriteString(m.styles.muted.Render(strings.Repeat("-", lipgloss.Width(header))) + "\n")
 
-		sessionStr := fmt.Sprintf("%-15s %-25s %-10s",
-			repoName,
-			session.Branch,
-			formatAge(session.Age()),
+	start, end := m.visibleRange()
+	for i := start; i < end; i++ {
+		session := m.sessions[i]
+
+		sessionStr := fmt.Sprintf("%s %s %s %s",
+			fit(repoName(session.RepoName, session.RepoPath), cols.repo),
+			fit(branchLabel(session), cols.branch),
+			fit(m.formatAge(session), createdWidth),
+			fit(formatStatus(session), statusWidth),
 		)
 
+		prefix := [2]rune{' ', ' '}
+		if m.cursor == i {
+			prefix[0] = '>'
+		}
+		if m.isMarked(i) {
+			prefix[1] = '•'
+		}
+
+		row := fmt.Sprintf("%s%s", string(prefix[:]), sessionStr)
 		if m.cursor == i {
-			s.WriteString(highlightStyle.Render(fmt.Sprintf("> %s", sessionStr)))
+			s.WriteString(m.styles.highlight.Render(row))
 		} else {
-			s.WriteString(fmt.Sprintf("  %s", sessionStr))
+			s.WriteString(row)
 		}
 
 		s.WriteString("\n")
 	}
 
-	s.WriteString("\nPress 'enter' or 'space' to switch to selected session\n")
-	s.WriteString("Press 'q' to quit\n")
+	if m.refreshErr != nil {
+		s.WriteString(m.styles.warning.Render(fmt.Sprintf("Refresh failed: %v", m.refreshErr)) + "\n")
+	}
+	if m.actionErr != nil {
+		s.WriteString(m.styles.warning.Render(fmt.Sprintf("Error: %v", m.actionErr)) + "\n")
+	}
+	if m.restoring != "" {
+		s.WriteString(m.styles.muted.Render(fmt.Sprintf("Restoring %s…", m.restoring)) + "\n")
+	}
+
+	footer := m.helpLine()
+	if marked := m.markedCount(); marked > 0 {
+		footer = fmt.Sprintf("%d marked • %s", marked, footer)
+	}
+	if start > 0 || end < len(m.sessions) {
+		footer = fmt.Sprintf("%d-%d of %d • %s", start+1, end, len(m.sessions), footer)
+	}
+	s.WriteString("\n" + m.styles.muted.Render(footer))
 
 	return s.String()
 }
 
-func formatAge(duration time.Duration) string {
-	if duration < time.Hour {
-		return fmt.Sprintf("%dm", int(duration.Minutes()))
-	} else if duration < 24*time.Hour {
-		return fmt.Sprintf("%dh", int(duration.Hours()))
-	} else {
-		return fmt.Sprintf("%dd", int(duration.Hours()/24))
+// visibleRange returns the slice of sessions that fits in the viewport.
+func (m model) visibleRange() (int, int) {
+	rows := m.listRows()
+	if rows == 0 {
+		return 0, len(m.sessions)
+	}
+	return m.offset, min(m.offset+rows, len(m.sessions))
+}
+
+func (m model) helpLine() string {
+	return fmt.Sprintf("%s switch • %s mark • %s range • %s kill • %s rm • %s rm+branch • %s preview • %s sort • %s quit",
+		keyLabel(m.keys.Select),
+		keyLabel(m.keys.Mark),
+		keyLabel(m.keys.Visual),
+		keyLabel(m.keys.KillSession),
+		keyLabel(m.keys.RemoveWorktree),
+		keyLabel(m.keys.RemoveBranch),
+		keyLabel(m.keys.Preview),
+		keyLabel(m.keys.Sort),
+		keyLabel(m.keys.Quit),
+	)
+}
+
+// The age column shows creation time, or last access when sorting by most recently used.
+func (m model) ageLabel() string {
+	if m.sortRecent {
+		return "USED"
+	}
+	return "CREATED"
+}
+
+func (m model) formatAge(session state.SessionInfo) string {
+	if m.sortRecent {
+		return utils.FormatAge(session.TimeSinceAccessed())
+	}
+	return utils.FormatAge(session.Age())
+}
+
+func keyLabel(bindings []string) string {
+	if len(bindings) == 0 {
+		return "(unbound)"
+	}
+	if bindings[0] == " " {
+		return "space"
+	}
+	return bindings[0]
+}
+
+// branchLabel is the branch of a session, or what it's for if it has none.
+func branchLabel(session state.SessionInfo) string {
+	if session.IsCommon() {
+		return "(common)"
+	}
+	return session.Branch
+}
+
+func formatStatus(session state.SessionInfo) string {
+	status := string(session.Status)
+	if session.Attached > 0 {
+		status = fmt.Sprintf("attached(%d)", session.Attached)
+	}
+	if session.Dirty {
+		status += "*"
 	}
+	return status
 }
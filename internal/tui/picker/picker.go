@@ -0,0 +1,201 @@
+package picker
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/discovery"
+)
+
+// Entry is a branch of a project that can be picked.
+type Entry struct {
+	Project  discovery.Project
+	Branch   string
+	Worktree bool
+}
+
+func (e Entry) text() string {
+	return strings.ToLower(e.Project.Name + " " + e.Branch)
+}
+
+// Entries lists every branch of every project.
+func Entries(projects []discovery.Project) []Entry {
+	var entries []Entry
+	for _, project := range projects {
+		for _, branch := range project.Branches {
+			_, worktree := project.Worktrees[branch]
+			entries = append(entries, Entry{Project: project, Branch: branch, Worktree: worktree})
+		}
+	}
+	return entries
+}
+
+type model struct {
+	entries  []Entry
+	matches  []int
+	query    string
+	cursor   int
+	offset   int
+	height   int
+	styles   styles
+	selected *Entry
+}
+
+type styles struct {
+	highlight lipgloss.Style
+	header    lipgloss.Style
+	muted     lipgloss.Style
+	hint      lipgloss.Style
+}
+
+func Create(entries []Entry, cfg *config.Config) tea.Program {
+	m := model{
+		entries: entries,
+		styles: styles{
+			highlight: lipgloss.NewStyle().
+				Background(lipgloss.Color(cfg.Theme.HighlightBackground)).
+				Foreground(lipgloss.Color(cfg.Theme.HighlightForeground)).
+				Bold(true),
+			header: lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Header)).Bold(true),
+			muted:  lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Muted)),
+			hint:   lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Hint)),
+		},
+	}
+	m.filter()
+	return *tea.NewProgram(m)
+}
+
+// Selected returns the entry picked in the finished picker, if any.
+func Selected(final tea.Model) (Entry, bool) {
+	if m, ok := final.(model); ok && m.selected != nil {
+		return *m.selected, true
+	}
+	return Entry{}, false
+}
+
+func (m model) Init() tea.Cmd {
+	return nil
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		last := max(len(m.matches)-1, 0)
+		page := max(m.rows(), 1)
+
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			return m, tea.Quit
+		case tea.KeyEnter:
+			if len(m.matches) > 0 {
+				entry := m.entries[m.matches[m.cursor]]
+				m.selected = &entry
+			}
+			return m, tea.Quit
+		case tea.KeyUp, tea.KeyCtrlP:
+			m.cursor = max(m.cursor-1, 0)
+		case tea.KeyDown, tea.KeyCtrlN:
+			m.cursor = min(m.cursor+1, last)
+		case tea.KeyPgUp:
+			m.cursor = max(m.cursor-page, 0)
+		case tea.KeyPgDown:
+			m.cursor = min(m.cursor+page, last)
+		case tea.KeyBackspace:
+			if m.query != "" {
+				runes := []rune(m.query)
+				m.query = string(runes[:len(runes)-1])
+				m.filter()
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.query += string(msg.Runes)
+			m.filter()
+		}
+	}
+
+	m.scrollToCursor()
+	return m, nil
+}
+
+// filter keeps the entries matching every word of the query.
+func (m *model) filter() {
+	words := strings.Fields(strings.ToLower(m.query))
+	m.matches = m.matches[:0]
+	for i, entry := range m.entries {
+		text := entry.text()
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			m.matches = append(m.matches, i)
+		}
+	}
+	m.cursor = 0
+	m.offset = 0
+}
+
+// rows is how many entries fit below the header and query, and above the footer.
+func (m model) rows() int {
+	if m.height == 0 {
+		return 0
+	}
+	return max(m.height-6, 1)
+}
+
+func (m *model) scrollToCursor() {
+	rows := m.rows()
+	if rows == 0 {
+		return
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+}
+
+func (m model) View() string {
+	var s strings.Builder
+	s.WriteString(m.styles.header.Render("TWT Projects:") + "\n\n")
+	s.WriteString(fmt.Sprintf("> %s\n\n", m.query))
+
+	nameWidth := 0
+	for _, i := range m.matches {
+		nameWidth = max(nameWidth, len(m.entries[i].Project.Name))
+	}
+
+	start, end := 0, len(m.matches)
+	if rows := m.rows(); rows > 0 {
+		start, end = m.offset, min(m.offset+rows, len(m.matches))
+	}
+	for i := start; i < end; i++ {
+		entry := m.entries[m.matches[i]]
+		row := fmt.Sprintf("%-*s %s", nameWidth, entry.Project.Name, entry.Branch)
+		suffix := ""
+		if entry.Worktree {
+			suffix = " (worktree)"
+		}
+		if i == m.cursor {
+			s.WriteString(m.styles.highlight.Render("> "+row+suffix) + "\n")
+		} else {
+			s.WriteString("  " + row + m.styles.hint.Render(suffix) + "\n")
+		}
+	}
+	if len(m.matches) == 0 {
+		s.WriteString(m.styles.muted.Render("  no matches") + "\n")
+	}
+
+	footer := fmt.Sprintf("%d of %d • enter go • esc quit", len(m.matches), len(m.entries))
+	s.WriteString("\n" + m.styles.muted.Render(footer))
+	return s.String()
+}
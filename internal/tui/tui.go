@@ -4,14 +4,41 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/fatih/color"
+	"github.com/j-clemons/twt/internal/config"
 	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
 	"github.com/j-clemons/twt/internal/tui/list"
+	"github.com/j-clemons/twt/internal/tui/picker"
 )
 
-func RunListTui(sessions []state.SessionInfo) {
-	p := list.Create(sessions)
-	if _, err := p.Run(); err != nil {
+func RunListTui(load list.Loader, sessions []state.SessionInfo) {
+	cfg, err := config.Load()
+	if err != nil {
+		color.Yellow("Warning: %v, using default theme and keys", err)
+	}
+
+	p := list.Create(load, sessions, cfg)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if selected := list.Selected(final); selected != "" {
+		if err := tmux.AttachOrSwitch(selected); err != nil {
+			color.Red("%s", err)
+		}
+	}
+}
+
+// RunProjectPicker lets the user pick a branch of any of the projects.
+func RunProjectPicker(entries []picker.Entry, cfg *config.Config) (picker.Entry, bool) {
+	p := picker.Create(entries, cfg)
+	final, err := p.Run()
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	return picker.Selected(final)
 }
@@ -33,16 +33,3 @@ func GetScriptsDirPath() (string, error) {
 	}
 	return path, nil
 }
-
-func GetScriptsDirPathForCommand(command string) (string, error) {
-	scriptsDir, err := GetScriptsDirPath()
-	if err != nil {
-		return "", err
-	}
-
-	path := fmt.Sprintf("%s/%s", scriptsDir, command)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return "", errors.New("Scripts dir doesn't exist.")
-	}
-	return path, nil
-}
@@ -0,0 +1,36 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		name            string
+		input           string
+		expected        time.Duration
+		expectedSuccess bool
+	}{
+		{name: "Days", input: "3d", expected: 72 * time.Hour, expectedSuccess: true},
+		{name: "Days and hours", input: "1d12h", expected: 36 * time.Hour, expectedSuccess: true},
+		{name: "Hours only", input: "90m", expected: 90 * time.Minute, expectedSuccess: true},
+		{name: "Invalid days", input: "xd", expectedSuccess: false},
+		{name: "Invalid remainder", input: "2dfoo", expectedSuccess: false},
+	}
+
+	for _, c := range cases {
+		duration, err := utils.ParseDuration(c.input)
+		if c.expectedSuccess && err != nil {
+			t.Fatalf("%s: Expected success but got error: %s (%s)", c.name, err, c.input)
+		}
+		if !c.expectedSuccess && err == nil {
+			t.Fatalf("%s: Expected error but got success (%s)", c.name, c.input)
+		}
+		if c.expectedSuccess && duration != c.expected {
+			t.Fatalf("%s: Expected %s but got %s", c.name, c.expected, duration)
+		}
+	}
+}
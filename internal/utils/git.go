@@ -13,6 +13,32 @@ func GenerateSessionNameFromBranch(branchName string) string {
 	return projectName + "_" + sanitizedBranch
 }
 
+// GenerateSessionNameForRepo names a session like GenerateSessionNameFromBranch, for a repo
+// other than the one in the current dir.
+func GenerateSessionNameForRepo(baseDir, branchName string) string {
+	sanitizedBranch := strings.Replace(branchName, "/", "__", -1)
+	return projectNameFromDir(baseDir) + "_" + sanitizedBranch
+}
+
+// GenerateBaseSessionName names the session for the repo's base dir, which isn't tied to a
+// branch.
+func GenerateBaseSessionName(baseDir string) string {
+	return projectNameFromDir(baseDir)
+}
+
+// GenerateCommonSessionName names the session in the repo's common files dir. The + keeps
+// it apart from branch sessions, whose names always have an _ after the project name.
+func GenerateCommonSessionName(baseDir string) string {
+	return projectNameFromDir(baseDir) + "+common"
+}
+
+// GenerateWorkspaceSessionName names the session of a workspace for a branch. The +ws_ keeps
+// it apart from the sessions of a repo with the same name as the workspace.
+func GenerateWorkspaceSessionName(workspace, branchName string) string {
+	sanitizedBranch := strings.Replace(branchName, "/", "__", -1)
+	return projectNameFromDir(workspace) + "+ws_" + sanitizedBranch
+}
+
 func GenerateWorktreeNameFromBranch(branchName string) string {
 	return strings.Replace(branchName, "/", "__", -1)
 }
@@ -22,6 +48,10 @@ func getProjectName() string {
 	if err != nil {
 		return "unknown"
 	}
+	return projectNameFromDir(baseDir)
+}
+
+func projectNameFromDir(baseDir string) string {
 	projectName := filepath.Base(baseDir)
 	// Replace dots with underscores to avoid tmux session name conflicts
 	projectName = strings.Replace(projectName, ".", "_", -1)
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+type AdoptStatus string
+
+const (
+	Adopted           AdoptStatus = "adopted"
+	AlreadyRegistered AdoptStatus = "already registered"
+	Skipped           AdoptStatus = "skipped"
+)
+
+type AdoptResult struct {
+	Worktree    git.Worktree
+	SessionName string
+	Status      AdoptStatus
+	// How the tmux session was matched, empty if no session is running for the worktree
+	MatchedBy string
+	Reason    string
+	Err       error
+}
+
+// AdoptRepo registers every worktree of the repo at repoPath that twt doesn't know about yet,
+// e.g. ones created with plain `git worktree add`. Running tmux sessions are matched by the
+// name twt would give them, or failing that by a pane working in the worktree.
+func AdoptRepo(repoPath string) ([]AdoptResult, error) {
+	worktrees, err := git.ListWorktrees(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := state.RegisterRepo(repoPath); err != nil {
+		return nil, err
+	}
+
+	current, err := state.LoadState()
+	if err != nil {
+		return nil, err
+	}
+	registeredPaths := make(map[string]bool)
+	for _, session := range current.Sessions {
+		registeredPaths[filepath.Clean(session.WorktreePath)] = true
+	}
+
+	running := tmux.ListAttachedClients()
+	paneDirs := tmux.ListPaneDirectories()
+	repoName := filepath.Base(repoPath)
+
+	var results []AdoptResult
+	for _, worktree := range worktrees {
+		result := AdoptResult{Worktree: worktree}
+
+		switch {
+		case worktree.Bare:
+			continue
+		case worktree.Detached || worktree.Branch == "":
+			result.Status = Skipped
+			result.Reason = "detached HEAD"
+			results = append(results, result)
+			continue
+		case registeredPaths[filepath.Clean(worktree.Path)]:
+			result.Status = AlreadyRegistered
+			results = append(results, result)
+			continue
+		}
+
+		result.SessionName = utils.GenerateSessionNameForRepo(repoPath, worktree.Branch)
+		if _, ok := running[result.SessionName]; ok {
+			result.MatchedBy = "name"
+		} else if name := sessionWorkingIn(worktree.Path, paneDirs); name != "" {
+			result.SessionName = name
+			result.MatchedBy = "pane directory"
+		}
+
+		if _, exists := current.Sessions[result.SessionName]; exists {
+			result.Status = Skipped
+			result.Reason = "session name already registered for another worktree"
+			results = append(results, result)
+			continue
+		}
+
+		result.Err = state.RegisterSession(result.SessionName, repoPath, repoName, worktree.Branch, worktree.Path)
+		result.Status = Adopted
+		current.Sessions[result.SessionName] = state.SessionInfo{Name: result.SessionName}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// AdoptCurrentSession registers the current tmux session for the worktree of the current
+// dir, whatever the session is called, unless the worktree or session name is already
+// registered.
+func AdoptCurrentSession() (AdoptResult, error) {
+	sessionName, err := tmux.GetCurrentSessionName()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+	repoPath, err := git.GetBaseDir()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+	worktreePath, err := git.GetWorktreeRoot()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+
+	result := AdoptResult{
+		Worktree:    git.Worktree{Path: worktreePath, Branch: branch},
+		SessionName: sessionName,
+		MatchedBy:   "current session",
+	}
+
+	current, err := state.LoadState()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+	// Like AdoptRepo, never overwrite a session or register a worktree twice
+	for _, session := range current.Sessions {
+		if filepath.Clean(session.WorktreePath) == filepath.Clean(worktreePath) {
+			result.SessionName = session.Name
+			result.Status = AlreadyRegistered
+			return result, nil
+		}
+	}
+	if _, exists := current.Sessions[sessionName]; exists {
+		result.Status = Skipped
+		result.Reason = "session name already registered for another worktree"
+		return result, nil
+	}
+
+	result.Status = Adopted
+	result.Err = state.RegisterSession(sessionName, repoPath, filepath.Base(repoPath), branch, worktreePath)
+	return result, nil
+}
+
+// KnownRepos returns the registered repos, and any others twt has sessions for.
+func KnownRepos() ([]string, error) {
+	current, err := state.LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var repos []string
+	for path := range current.Repos {
+		seen[path] = true
+		repos = append(repos, path)
+	}
+	for _, session := range current.Sessions {
+		if session.RepoPath != "" && !seen[session.RepoPath] {
+			seen[session.RepoPath] = true
+			repos = append(repos, session.RepoPath)
+		}
+	}
+	sort.Strings(repos)
+
+	if len(repos) == 0 {
+		return nil, errors.New("No known repos - run adopt from inside a repo first")
+	}
+	return repos, nil
+}
+
+// sessionWorkingIn finds a session with a pane inside dir. Sessions are checked in name
+// order so the match is stable.
+func sessionWorkingIn(dir string, paneDirs map[string][]string) string {
+	names := make([]string, 0, len(paneDirs))
+	for name := range paneDirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dir = filepath.Clean(dir)
+	for _, name := range names {
+		for _, paneDir := range paneDirs[name] {
+			paneDir = filepath.Clean(paneDir)
+			if paneDir == dir || strings.HasPrefix(paneDir, dir+string(filepath.Separator)) {
+				return name
+			}
+		}
+	}
+	return ""
+}
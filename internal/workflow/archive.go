@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/j-clemons/twt/internal/archive"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+// ArchiveSession saves the uncommitted work of a session's worktree so it survives a forced
+// removal. Clean worktrees are archived too, the archive still records the branch tip.
+func ArchiveSession(session state.SessionInfo) (*archive.Archive, error) {
+	if !dirExists(session.WorktreePath) {
+		return nil, fmt.Errorf("worktree %s no longer exists", session.WorktreePath)
+	}
+	return archive.Create(session.Name, session.RepoPath, session.Branch, session.WorktreePath)
+}
+
+// PlanRestoreArchive lists the steps to recreate an archived worktree at its old path,
+// reapply its uncommitted work, and register and start its session. The branch is recreated
+// at the archived tip if it was deleted, and if it moved on since, the work is merged in
+// three-way. If a step fails everything is undone and the archive kept, otherwise it's
+// deleted once everything is back.
+func PlanRestoreArchive(saved archive.Archive) (*plan.Plan, error) {
+	session := archivedSession(saved)
+	if dirExists(saved.WorktreePath) {
+		return nil, fmt.Errorf("%s already exists, remove it before restoring", saved.WorktreePath)
+	}
+	if tmux.HasSession(saved.SessionName) {
+		return nil, fmt.Errorf("session %s is already running", saved.SessionName)
+	}
+
+	p := plan.New(fmt.Sprintf("restore %s", saved.SessionName))
+	startPoint := ""
+	if !git.HasBranchInRepo(saved.RepoPath, saved.Branch) {
+		startPoint = saved.Head
+	} else if tip, err := git.BranchTip(saved.RepoPath, saved.Branch); err != nil {
+		return nil, err
+	} else if tip != saved.Head {
+		p.Note("%s moved on since it was archived, its uncommitted work is merged in three-way", saved.Branch)
+	}
+	addWorktreeSteps(p, saved.RepoPath, saved.WorktreePath, saved.Branch, startPoint)
+
+	archivedAt := saved.CreatedAt.Format("2006-01-02 15:04")
+	p.Add(plan.Git, fmt.Sprintf("reapply the uncommitted work archived %s", archivedAt), func() error {
+		if err := saved.Apply(saved.WorktreePath); err != nil {
+			return fmt.Errorf("%w; the archive in %s was kept", err, saved.Dir)
+		}
+		return nil
+	})
+
+	env, err := sessionEnv(session)
+	if err != nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("create session %s in %s", session.Name, session.WorktreePath)
+	p.Add(plan.Tmux, withEnv(description, env), func() error {
+		return tmux.CreateSessionInDirectory(session.Name, session.WorktreePath, env...)
+	}).OnRollback(fmt.Sprintf("kill session %s", session.Name), func() error {
+		tmux.KillSession(session.Name)
+		return nil
+	})
+	p.Add(plan.State, fmt.Sprintf("register session %s", session.Name), func() error {
+		return state.RegisterSession(session.Name, session.RepoPath, session.RepoName, session.Branch, session.WorktreePath)
+	}).OnRollback(fmt.Sprintf("unregister session %s", session.Name), func() error {
+		return state.UnregisterSession(session.Name)
+	})
+
+	p.Add(plan.Filesystem, fmt.Sprintf("delete the archive in %s", saved.Dir), saved.Delete)
+	return p, nil
+}
+
+// archivedSession returns the session an archive is restored as.
+func archivedSession(saved archive.Archive) state.SessionInfo {
+	return state.SessionInfo{
+		Name:         saved.SessionName,
+		RepoPath:     saved.RepoPath,
+		RepoName:     filepath.Base(saved.RepoPath),
+		Branch:       saved.Branch,
+		WorktreePath: saved.WorktreePath,
+	}
+}
@@ -0,0 +1,61 @@
+package workflow_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/archive"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+func TestRestoreArchiveRollsBack(t *testing.T) {
+	dir := isolate(t, "")
+	repoPath := filepath.Join(dir, "api")
+	git(t, dir, "init", "--quiet", "-b", "main", repoPath)
+	if err := os.WriteFile(filepath.Join(repoPath, "file.txt"), []byte("1\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, repoPath, "add", ".")
+	git(t, repoPath, "commit", "--quiet", "-m", "init")
+
+	worktreePath := filepath.Join(dir, "feature")
+	git(t, repoPath, "worktree", "add", "--quiet", "-b", "feature", worktreePath)
+	if err := os.WriteFile(filepath.Join(worktreePath, "file.txt"), []byte("one\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := archive.Create("api_feature", repoPath, "feature", worktreePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := workflow.PlanRestoreArchive(*saved); err == nil {
+		t.Fatalf("Worktree still there: Expected error but got success")
+	}
+
+	// The branch moves on with a change conflicting with the archived one
+	if err := os.WriteFile(filepath.Join(worktreePath, "file.txt"), []byte("uno\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, worktreePath, "commit", "--quiet", "-am", "moved on")
+	git(t, repoPath, "worktree", "remove", "--force", worktreePath)
+
+	p, err := workflow.PlanRestoreArchive(*saved)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if out := printed(t, p); !strings.Contains(out, "feature moved on since it was archived") {
+		t.Fatalf("Expected a note that the branch moved on but got:\n%s", out)
+	}
+	if err := p.Execute(); err == nil || !strings.Contains(err.Error(), "couldn't apply unstaged changes") {
+		t.Fatalf("Conflicting work: Expected the work not to apply but got: %v", err)
+	}
+
+	if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+		t.Fatalf("Expected the worktree to be removed again but got: %v", err)
+	}
+	if archives, err := archive.List(repoPath); err != nil || len(archives) != 1 {
+		t.Fatalf("Expected the archive to be kept but got %d archives, %v", len(archives), err)
+	}
+}
@@ -0,0 +1,165 @@
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/templates"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+const newDirPerm = 0700
+
+// CommonDir returns the common files dir of the repo at baseDir, or an error if it doesn't
+// exist.
+func CommonDir(baseDir string) (string, error) {
+	commonDir := filepath.Join(baseDir, "common")
+	if !dirExists(commonDir) {
+		return "", errors.New("Common files dir doesn't exist - create it with 'twt common init'")
+	}
+	return commonDir, nil
+}
+
+// PlanCommon lists the steps to switch to the common session of the repo at baseDir,
+// starting it in the repo's common files dir and registering it if needed. Outside tmux it
+// attaches instead.
+func PlanCommon(baseDir string, removeCurrentSession bool, currentSession string) (*plan.Plan, error) {
+	p := plan.New("common")
+
+	commonDir, err := CommonDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	sessionName := utils.GenerateCommonSessionName(baseDir)
+	_, registered, err := state.GetSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tmux.HasSession(sessionName) {
+		p.Add(plan.Tmux, fmt.Sprintf("create session %s in %s", sessionName, commonDir), func() error {
+			return createOrRestoreSession(sessionName, commonDir)
+		}).OnRollback(fmt.Sprintf("kill session %s", sessionName), func() error {
+			tmux.KillSession(sessionName)
+			return nil
+		})
+	}
+	if registered {
+		p.Add(plan.State, fmt.Sprintf("record access to %s", sessionName), func() error {
+			state.UpdateLastAccessed(sessionName)
+			return nil
+		})
+	} else {
+		p.Add(plan.State, fmt.Sprintf("register common session %s", sessionName), func() error {
+			return state.RegisterCommonSession(sessionName, baseDir, commonDir)
+		}).OnRollback(fmt.Sprintf("unregister session %s", sessionName), func() error {
+			return state.UnregisterSession(sessionName)
+		})
+	}
+
+	p.Add(plan.Tmux, tmux.SwitchDescription(sessionName), func() error {
+		return tmux.AttachOrSwitch(sessionName)
+	})
+	if removeCurrentSession && tmux.InsideTmux() && currentSession != sessionName {
+		p.Add(plan.Tmux, fmt.Sprintf("kill the current session %s", currentSession), func() error {
+			tmux.KillSession(currentSession)
+			return nil
+		})
+	}
+	return p, nil
+}
+
+// FileChange is a template file whose content differs from the one in the common files dir.
+type FileChange struct {
+	Path string
+	Old  []byte
+	New  []byte
+}
+
+// Diff renders the change as a unified diff of the file.
+func (c FileChange) Diff() (string, error) {
+	return git.DiffContents(c.Path, c.Old, c.New)
+}
+
+// PlanCommonInit lists the dirs and files to create in the common files dir of the repo at
+// baseDir from the template. Files that exist are left alone, unless force is set and they
+// differ from the template, in which case they're updated. Returns the files that differ,
+// updated or not.
+func PlanCommonInit(baseDir string, template templates.Template, force bool) (*plan.Plan, []FileChange, error) {
+	p := plan.New(fmt.Sprintf("common init --template %s", template.Name))
+
+	files, err := template.Files()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commonDir := filepath.Join(baseDir, "common")
+	if pathExists(commonDir) {
+		p.Note("%s exists", commonDir)
+	} else {
+		p.Add(plan.Filesystem, fmt.Sprintf("create dir %s", commonDir), func() error {
+			if err := os.Mkdir(commonDir, newDirPerm); err != nil {
+				return fmt.Errorf("dir %s couldn't be created: %w", commonDir, err)
+			}
+			return nil
+		}).OnRollback(fmt.Sprintf("remove dir %s", commonDir), func() error {
+			return os.RemoveAll(commonDir)
+		})
+	}
+
+	var changes []FileChange
+	for _, file := range files {
+		target := filepath.Join(commonDir, filepath.FromSlash(file.Path))
+		current, err := os.ReadFile(target)
+		switch {
+		case os.IsNotExist(err):
+			p.Add(plan.Filesystem, fmt.Sprintf("create %s", target), func() error {
+				return writeTemplateFile(target, file)
+			}).OnRollback(fmt.Sprintf("remove %s", target), func() error {
+				return os.Remove(target)
+			})
+
+		case err != nil:
+			return nil, nil, fmt.Errorf("couldn't read %s: %w", target, err)
+
+		case bytes.Equal(current, file.Content):
+			p.Note("%s is up to date", target)
+
+		default:
+			changes = append(changes, FileChange{Path: target, Old: current, New: file.Content})
+			if !force {
+				p.Note("%s differs from the template, skipping (use --force to update)", target)
+				continue
+			}
+			p.Add(plan.Filesystem, fmt.Sprintf("update %s", target), func() error {
+				return writeTemplateFile(target, file)
+			}).OnRollback(fmt.Sprintf("restore %s", target), func() error {
+				return os.WriteFile(target, current, file.Mode)
+			})
+		}
+	}
+	return p, changes, nil
+}
+
+func writeTemplateFile(target string, file templates.File) error {
+	if err := os.MkdirAll(filepath.Dir(target), newDirPerm); err != nil {
+		return fmt.Errorf("dir %s couldn't be created: %w", filepath.Dir(target), err)
+	}
+	if err := os.WriteFile(target, file.Content, file.Mode); err != nil {
+		return fmt.Errorf("file %s couldn't be written: %w", target, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(target, file.Mode)
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
@@ -0,0 +1,138 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/compose"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+// ComposeProject is the compose project of one of a session's worktrees.
+type ComposeProject struct {
+	Name string
+	Dir  string
+}
+
+// ComposeProjects returns the compose project of a session's worktree, or of each of a
+// workspace session's worktrees that has a compose file.
+func ComposeProjects(session state.SessionInfo) []ComposeProject {
+	var projects []ComposeProject
+	for _, worktree := range sessionWorktrees(session) {
+		if session.Workspace != "" && !compose.HasFile(worktree.worktreePath) {
+			continue
+		}
+		projects = append(projects, ComposeProject{
+			Name: compose.ProjectName(worktree.repoPath, session.Branch),
+			Dir:  worktree.worktreePath,
+		})
+	}
+	return projects
+}
+
+// composeEnv returns the env exporting the compose project of branch's worktree in the repo,
+// unless compose is turned off in the config.
+func composeEnv(repoPath, branch string) ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Compose.Enabled {
+		return nil, nil
+	}
+	return []string{compose.Env(compose.ProjectName(repoPath, branch))}, nil
+}
+
+// sessionEnv returns the env a session is created with. A workspace session's worktrees each
+// have their own compose project, so it doesn't export one.
+func sessionEnv(session state.SessionInfo) ([]string, error) {
+	if session.IsCommon() || session.Workspace != "" {
+		return nil, nil
+	}
+	return composeEnv(session.RepoPath, session.Branch)
+}
+
+// withEnv adds the env a step starts a session with to its description.
+func withEnv(description string, env []string) string {
+	if len(env) == 0 {
+		return description
+	}
+	return description + " with " + strings.Join(env, " ")
+}
+
+// addComposeDownSteps adds bringing down the compose project of each of the session's
+// worktrees that has a compose file and containers, after its resources are torn down. A
+// worktree deleted without twt has no compose file left, so its project is brought down by
+// name from the repo's base dir if it has containers. A project a resource's teardown
+// already brings down is left to it. With force a failure is a warning rather than stopping
+// the removal.
+func addComposeDownSteps(p *plan.Plan, target string, session state.SessionInfo, force bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if !cfg.Compose.Enabled {
+		return nil
+	}
+	settings := config.ScriptSettings{OnFailure: config.FailureAbort}
+	if force {
+		settings.OnFailure = config.FailureWarn
+	}
+
+	var existing []string
+	listed := false
+	args := append([]string{"down"}, cfg.Compose.DownArgs...)
+	for _, worktree := range sessionWorktrees(session) {
+		dir := worktree.worktreePath
+		if !dirExists(dir) {
+			dir = worktree.repoPath
+		} else if !compose.HasFile(dir) {
+			continue
+		}
+		project := compose.ProjectName(worktree.repoPath, session.Branch)
+		if resource, ok := composeResource(session, worktree.repoPath); ok {
+			p.Note("skip bringing down compose project %s, tearing down %s does", project, resource.Name)
+			continue
+		}
+		if !compose.Available(cfg.Compose.Command) {
+			p.Note("skip bringing down compose project %s, %s isn't installed", project, strings.Join(cfg.Compose.Command, " "))
+			continue
+		}
+		if !listed {
+			var err error
+			existing, err = compose.Projects(cfg.Compose.Command)
+			if err != nil {
+				p.Note("skip bringing down compose projects, couldn't list them: %s", err)
+				return nil
+			}
+			listed = true
+		}
+		if !slices.Contains(existing, project) {
+			continue
+		}
+
+		commandLine := strings.Join(append(slices.Clone(cfg.Compose.Command), args...), " ")
+		description := fmt.Sprintf("bring down compose project %s: %s", project, commandLine)
+		p.AddFor(target, plan.Script, hookDescription(description, settings), hookStep(p, settings, func() error {
+			if err := compose.Run(cfg.Compose.Command, project, dir, args...); err != nil {
+				return fmt.Errorf("compose down of %s failed: %w", project, err)
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// composeResource returns the session's resource in the repo whose teardown brings its
+// compose project down, as its manifest declared, if there's one.
+func composeResource(session state.SessionInfo, repoPath string) (state.ResourceInfo, bool) {
+	for _, resource := range session.Resources {
+		if resource.RepoPath == repoPath && resource.ComposeDown {
+			return resource, true
+		}
+	}
+	return state.ResourceInfo{}, false
+}
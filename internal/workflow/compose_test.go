@@ -0,0 +1,254 @@
+package workflow_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/archive"
+	"github.com/j-clemons/twt/internal/compose"
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+// isolate keeps twt's config, state and tmux server to the test, and writes the config.
+func isolate(t *testing.T, cfg string) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("HOME", dir)
+	t.Setenv("TMUX_TMPDIR", dir)
+	t.Setenv("TMUX", "")
+	if cfg != "" {
+		configDir, err := config.Dir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(configDir, config.ConfigFileName), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func printed(t *testing.T, p *plan.Plan) string {
+	var out bytes.Buffer
+	if err := p.Print(&out, false); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=twt", "-c", "user.email=twt@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestSessionEnv(t *testing.T) {
+	env := "COMPOSE_PROJECT_NAME=" + compose.ProjectName("/code/api.git", "feature")
+	session := state.SessionInfo{Name: "api_git_feature", RepoPath: "/code/api.git", Branch: "feature", WorktreePath: "/code/api.git/feature"}
+	withLayout := session
+	withLayout.Layout = &tmux.Layout{Windows: []tmux.Window{{Name: "editor"}}}
+	inWorkspace := session
+	inWorkspace.Name, inWorkspace.Workspace = "shop_feature", "shop"
+	saved := archive.Archive{SessionName: session.Name, RepoPath: session.RepoPath, Branch: session.Branch, WorktreePath: session.WorktreePath}
+
+	cases := []struct {
+		name     string
+		config   string
+		plan     func() (*plan.Plan, error)
+		expected bool
+	}{
+		{name: "Restore", plan: func() (*plan.Plan, error) { return workflow.PlanRestoreSession(session) }, expected: true},
+		{name: "Restore layout", plan: func() (*plan.Plan, error) { return workflow.PlanRestoreSession(withLayout) }, expected: true},
+		{name: "Restore archive", plan: func() (*plan.Plan, error) { return workflow.PlanRestoreArchive(saved) }, expected: true},
+		{name: "Restore workspace session", plan: func() (*plan.Plan, error) { return workflow.PlanRestoreSession(inWorkspace) }, expected: false},
+		{
+			name:     "Compose turned off",
+			config:   `{"compose":{"enabled":false}}`,
+			plan:     func() (*plan.Plan, error) { return workflow.PlanRestoreSession(session) },
+			expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		isolate(t, c.config)
+		p, err := c.plan()
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		if out := printed(t, p); strings.Contains(out, env) != c.expected {
+			t.Fatalf("%s: Expected %s in the plan to be %t but got:\n%s", c.name, env, c.expected, out)
+		}
+	}
+}
+
+func TestPlansFailOnBrokenConfig(t *testing.T) {
+	isolate(t, `{"compose":`)
+	session := state.SessionInfo{Name: "api_git_feature", RepoPath: "/code/api.git", Branch: "feature", WorktreePath: "/code/api.git/feature"}
+
+	if _, err := workflow.PlanRestoreSession(session); err == nil {
+		t.Fatalf("Restore: Expected error but got success")
+	}
+	if _, err := workflow.PlanRemoveSession(session, workflow.RemoveSessionWorktree, workflow.RemoveOptions{}); err == nil {
+		t.Fatalf("Remove: Expected error but got success")
+	}
+	if _, err := workflow.PlanPrune([]state.SessionInfo{session}); err == nil {
+		t.Fatalf("Prune: Expected error but got success")
+	}
+}
+
+func TestPlanGoSessionEnv(t *testing.T) {
+	dir := isolate(t, "")
+	baseDir := filepath.Join(dir, "api.git")
+	git(t, dir, "init", "--quiet", "--bare", baseDir)
+	baseDir, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(baseDir)
+
+	p, err := workflow.PlanGo(workflow.GoOptions{Branch: "feature"})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	env := "COMPOSE_PROJECT_NAME=" + compose.ProjectName(baseDir, "feature")
+	if out := printed(t, p); !strings.Contains(out, "create session api_git_feature in "+filepath.Join(baseDir, "feature")+" with "+env) {
+		t.Fatalf("Expected the session to be created with %s but got:\n%s", env, out)
+	}
+}
+
+func TestScriptsGetComposeEnv(t *testing.T) {
+	dir := isolate(t, "")
+	repoPath := filepath.Join(dir, "api")
+	worktreePath := filepath.Join(dir, "feature")
+	git(t, dir, "init", "--quiet", repoPath)
+	git(t, repoPath, "commit", "--quiet", "--allow-empty", "-m", "init")
+	git(t, repoPath, "worktree", "add", "--quiet", "-b", "feature", worktreePath)
+
+	out := filepath.Join(dir, "project")
+	session := state.SessionInfo{
+		Name:         "api_feature",
+		RepoPath:     repoPath,
+		Branch:       "feature",
+		WorktreePath: worktreePath,
+		Resources: []state.ResourceInfo{
+			{Name: "db", RepoPath: repoPath, Dir: worktreePath, Teardown: `echo "$COMPOSE_PROJECT_NAME" > ` + out},
+		},
+	}
+	p, err := workflow.PlanRemoveSession(session, workflow.RemoveSessionWorktree, workflow.RemoveOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+
+	project, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := compose.ProjectName(repoPath, "feature"); strings.TrimSpace(string(project)) != expected {
+		t.Fatalf("Expected the teardown to get project %s but got %q", expected, project)
+	}
+}
+
+func TestRemoveBringsDownCompose(t *testing.T) {
+	project := compose.ProjectName("/code/api.git", "feature")
+	cases := []struct {
+		name        string
+		command     string
+		noFile      bool
+		teardown    string
+		composeDown bool
+		expected    string
+		notExpected string
+	}{
+		{name: "Project exists", command: `["sh", "-c", "echo other; echo ` + project + `", "sh"]`, expected: "bring down compose project " + project},
+		{name: "No such project", command: `["sh", "-c", "echo other", "sh"]`, notExpected: "bring down compose project"},
+		{name: "Daemon down", command: `["sh", "-c", "exit 1", "sh"]`, expected: "couldn't list them", notExpected: "bring down compose project"},
+		{name: "No compose file", command: `["sh", "-c", "echo ` + project + `", "sh"]`, noFile: true, notExpected: "compose project"},
+		{
+			name:     "Resource teardown not declared to bring it down",
+			command:  `["sh", "-c", "echo ` + project + `", "sh"]`,
+			teardown: "docker compose down --volumes",
+			expected: "bring down compose project " + project,
+		},
+		{
+			name:        "Torn down by a resource",
+			command:     `["sh", "-c", "echo ` + project + `", "sh"]`,
+			teardown:    "docker compose down --volumes",
+			composeDown: true,
+			expected:    "tearing down stack does",
+			notExpected: "bring down compose project",
+		},
+	}
+
+	for _, c := range cases {
+		dir := isolate(t, `{"compose":{"command":`+c.command+`}}`)
+		if !c.noFile {
+			if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte("services: {}\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		session := state.SessionInfo{Name: "api_git_feature", RepoPath: "/code/api.git", Branch: "feature", WorktreePath: dir}
+		if c.teardown != "" {
+			session.Resources = []state.ResourceInfo{{Name: "stack", RepoPath: session.RepoPath, Dir: dir, Teardown: c.teardown, ComposeDown: c.composeDown}}
+		}
+
+		p, err := workflow.PlanRemoveSession(session, workflow.RemoveSessionWorktree, workflow.RemoveOptions{})
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		out := printed(t, p)
+		if c.expected != "" && !strings.Contains(out, c.expected) {
+			t.Fatalf("%s: Expected %q in the plan but got:\n%s", c.name, c.expected, out)
+		}
+		if c.notExpected != "" && strings.Contains(out, c.notExpected) {
+			t.Fatalf("%s: Expected no %q in the plan but got:\n%s", c.name, c.notExpected, out)
+		}
+	}
+}
+
+func TestPruneBringsDownCompose(t *testing.T) {
+	dir := t.TempDir()
+	repoPath := filepath.Join(dir, "api")
+	git(t, dir, "init", "--quiet", repoPath)
+	repoPath, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := compose.ProjectName(repoPath, "feature")
+	out := filepath.Join(dir, "down")
+	script := `if [ "$1" = ls ]; then echo ` + project + `; else echo "$PWD $COMPOSE_PROJECT_NAME $*" > ` + out + `; fi`
+	isolate(t, `{"compose":{"command":["sh", "-c", "`+strings.ReplaceAll(script, `"`, `\"`)+`", "sh"], "down_args": []}}`)
+
+	session := state.SessionInfo{Name: "api_feature", RepoPath: repoPath, Branch: "feature", WorktreePath: filepath.Join(dir, "feature")}
+	p, err := workflow.PlanPrune([]state.SessionInfo{session})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+	if printedPlan := printed(t, p); !strings.Contains(printedPlan, "bring down compose project "+project) {
+		t.Fatalf("Expected the project to be brought down but got:\n%s", printedPlan)
+	}
+	if err := p.Execute(); err != nil {
+		t.Fatalf("Expected success but got error: %s", err)
+	}
+
+	down, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := repoPath + " " + project + " down"; strings.TrimSpace(string(down)) != expected {
+		t.Fatalf("Expected compose to run as %q but got %q", expected, down)
+	}
+}
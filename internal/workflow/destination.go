@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+// ChooseDestination picks the session to switch to when leaving a session that's about to be
+// removed, trying each policy in order:
+//
+//   - previous: the session the client was on before this one
+//   - recent: the most recently used running twt session of the same repo
+//   - default-branch: the running session for the repo's default branch
+//   - base: a session in the repo's common dir, or base dir, created if needed
+//
+// The base session is always tried last so there's somewhere to go.
+func ChooseDestination(policies []string, repoPath, leaving string) (string, error) {
+	for _, policy := range policies {
+		if destination, ok := tryDestination(policy, repoPath, leaving); ok {
+			return destination, nil
+		}
+	}
+	return baseSession(repoPath)
+}
+
+func tryDestination(policy, repoPath, leaving string) (string, bool) {
+	switch policy {
+
+	case config.DestinationPrevious:
+		previous, err := tmux.GetPreviousSessionName()
+		if err != nil || previous == leaving || !tmux.HasSession(previous) {
+			return "", false
+		}
+		return previous, true
+
+	case config.DestinationRecent:
+		recent, ok := state.MostRecentActive(repoPath, leaving)
+		return recent.Name, ok
+
+	case config.DestinationDefaultBranch:
+		branch, err := git.DefaultBranch(repoPath)
+		if err != nil {
+			return "", false
+		}
+		sessionName := utils.GenerateSessionNameForRepo(repoPath, branch)
+		if sessionName == leaving || !tmux.HasSession(sessionName) {
+			return "", false
+		}
+		return sessionName, true
+
+	case config.DestinationBase:
+		sessionName, err := baseSession(repoPath)
+		return sessionName, err == nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: unknown destination policy %q, skipping\n", policy)
+	return "", false
+}
+
+// baseSession returns the repo's common session, started in its common dir if there is
+// one, otherwise a session in its base dir, created if needed.
+func baseSession(repoPath string) (string, error) {
+	if commonDir, err := CommonDir(repoPath); err == nil {
+		sessionName := utils.GenerateCommonSessionName(repoPath)
+		if !tmux.HasSession(sessionName) {
+			if err := createOrRestoreSession(sessionName, commonDir); err != nil {
+				return "", err
+			}
+		}
+		if _, registered, err := state.GetSession(sessionName); err == nil && !registered {
+			state.RegisterCommonSession(sessionName, repoPath, commonDir)
+		}
+		return sessionName, nil
+	}
+
+	sessionName := utils.GenerateBaseSessionName(repoPath)
+	if tmux.HasSession(sessionName) {
+		return sessionName, nil
+	}
+
+	tmux.NewSessionWithDirectory(sessionName, repoPath)
+	if !tmux.HasSession(sessionName) {
+		return "", fmt.Errorf("couldn't create base session %s", sessionName)
+	}
+	return sessionName, nil
+}
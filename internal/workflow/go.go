@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
 	"github.com/j-clemons/twt/internal/state"
 	"github.com/j-clemons/twt/internal/tmux"
 	"github.com/j-clemons/twt/internal/utils"
@@ -16,80 +17,180 @@ type GoOptions struct {
 	RemoveCurrentSession bool
 	NoScripts            bool
 	CurrentSession       string
+	// Only create and register the worktree, without a session or scripts
+	NoTmux bool
 }
 
 func ExecuteGo(opts GoOptions) error {
+	p, err := PlanGo(opts)
+	if err != nil {
+		return err
+	}
+	return p.Execute()
+}
+
+// GoWorktreePath returns where go puts the worktree for branch in the current repo.
+func GoWorktreePath(branch string) (string, error) {
+	baseDir, err := git.GetBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, utils.GenerateWorktreeNameFromBranch(branch)), nil
+}
+
+// PlanGo works out what go needs to do for the branch: switch to its running session, start
+// a session in its existing worktree, or create the worktree (and branch) first. Outside
+// tmux the last step attaches to the session, replacing twt.
+func PlanGo(opts GoOptions) (*plan.Plan, error) {
 	sessionName := utils.GenerateSessionNameFromBranch(opts.Branch)
 	worktreeName := utils.GenerateWorktreeNameFromBranch(opts.Branch)
 
 	baseDir, err := git.GetBaseDir()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	worktreePath := filepath.Join(baseDir, worktreeName)
 
-	if tmux.HasSession(sessionName) {
-		tmux.SwitchToSession(sessionName)
-		if opts.RemoveCurrentSession {
-			tmux.KillSession(opts.CurrentSession)
-		}
-		return nil
+	p := plan.New(fmt.Sprintf("go %s", opts.Branch))
+
+	if !opts.NoTmux && tmux.HasSession(sessionName) {
+		p.Add(plan.State, fmt.Sprintf("record access to %s", sessionName), func() error {
+			state.UpdateLastAccessed(sessionName)
+			return nil
+		})
+		planSwitch(p, sessionName, opts)
+		return p, nil
 	}
 
-	worktreeExists := git.HasWorktree(opts.Branch)
-	if worktreeExists {
-		sessionDir := fmt.Sprintf("%s/%s", baseDir, worktreeName)
-		err = tmux.CreateSessionInDirectory(sessionName, sessionDir)
-		if err != nil {
-			return err
-		}
-		err = tmux.SetupWorktreeSession(sessionName, baseDir, worktreeName)
-	} else {
-		err = tmux.CreateSessionInDirectory(sessionName, baseDir)
-		if err != nil {
-			return err
-		}
-		branchIsNew := !git.HasBranch(opts.Branch, false)
-		err = git.CreateWorktreeInSession(sessionName, baseDir, worktreeName, opts.Branch, branchIsNew)
-		if err != nil {
-			return err
+	if !git.HasWorktree(opts.Branch) {
+		startPoint := ""
+		if !git.HasBranch(opts.Branch, false) {
+			startPoint = "HEAD"
 		}
+		addWorktreeSteps(p, baseDir, worktreePath, opts.Branch, startPoint)
+	}
 
-		err = git.WaitForWorktreeReady(baseDir, worktreeName, opts.Branch, 10*time.Second)
-		if err != nil {
-			tmux.KillSession(sessionName)
-			git.RemoveWorktree(worktreeName, opts.Branch, true, false)
-			return fmt.Errorf("worktree creation failed: %v", err)
+	saved, registered, err := state.GetSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	repoName := filepath.Base(baseDir)
+	register := func() {
+		step := p.Add(plan.State, fmt.Sprintf("register session %s", sessionName), func() error {
+			return state.RegisterSession(sessionName, baseDir, repoName, opts.Branch, worktreePath)
+		})
+		// A session registered before, e.g. hibernated, keeps its entry
+		if !registered {
+			step.OnRollback(fmt.Sprintf("unregister session %s", sessionName), func() error {
+				return state.UnregisterSession(sessionName)
+			})
 		}
+	}
 
-		tmux.KillSession(sessionName)
-		sessionDir := fmt.Sprintf("%s/%s", baseDir, worktreeName)
-		err = tmux.CreateSessionInDirectory(sessionName, sessionDir)
-		if err != nil {
-			return err
+	if opts.NoTmux {
+		if !registered {
+			register()
 		}
-		tmux.SendKeys(sessionName, "clear", "Enter")
+		return p, nil
 	}
 
+	env, err := composeEnv(baseDir, opts.Branch)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	description := fmt.Sprintf("create session %s in %s", sessionName, worktreePath)
+	if registered && saved.Layout != nil && saved.WorktreePath == worktreePath {
+		description = fmt.Sprintf("restore session %s with its saved layout (%d windows)", sessionName, len(saved.Layout.Windows))
 	}
+	p.Add(plan.Tmux, withEnv(description, env), func() error {
+		return createOrRestoreSession(sessionName, worktreePath, env...)
+	}).OnRollback(fmt.Sprintf("kill session %s", sessionName), func() error {
+		tmux.KillSession(sessionName)
+		return nil
+	})
+	p.Add(plan.Tmux, fmt.Sprintf("clear the screen in %s", sessionName), func() error {
+		return tmux.SetupWorktreeSession(sessionName, baseDir, worktreeName)
+	})
 
-	// Register session in state
-	repoName := filepath.Base(baseDir)
-	worktreePath := fmt.Sprintf("%s/%s", baseDir, worktreeName)
-	err = state.RegisterSession(sessionName, baseDir, repoName, opts.Branch, worktreePath)
-	if err != nil {
-		fmt.Printf("Warning: Failed to register session: %v\n", err)
+	register()
+
+	if err := planPostInitialization(p, baseDir, sessionName, worktreePath, opts); err != nil {
+		return nil, err
 	}
+	return p, nil
+}
 
-	return handlePostInitialization(sessionName, opts.NoScripts, opts.RemoveCurrentSession, opts.CurrentSession)
+// addWorktreeSteps adds creating the worktree for branch, and the branch itself from
+// startPoint unless it's empty, each undone if a later step fails.
+func addWorktreeSteps(p *plan.Plan, baseDir, worktreePath, branch, startPoint string) {
+	if startPoint != "" {
+		p.Add(plan.Git, fmt.Sprintf("create branch %s from %s in %s", branch, startPoint, filepath.Base(baseDir)), func() error {
+			return git.CreateBranch(baseDir, branch, startPoint)
+		}).OnRollback(fmt.Sprintf("delete branch %s", branch), func() error {
+			return errorsFrom(git.DeleteBranchFromRepo(baseDir, branch, true))
+		})
+	}
+	p.Add(plan.Git, fmt.Sprintf("add worktree %s for branch %s", worktreePath, branch), func() error {
+		if err := git.AddWorktree(baseDir, worktreePath, branch, ""); err != nil {
+			return err
+		}
+		if err := git.WaitForWorktreeReady(filepath.Dir(worktreePath), filepath.Base(worktreePath), branch, 10*time.Second); err != nil {
+			git.RemoveWorktreeFromRepo(baseDir, worktreePath, branch, true, false)
+			return fmt.Errorf("worktree creation failed: %w", err)
+		}
+		return nil
+	}).OnRollback(fmt.Sprintf("remove worktree %s", worktreePath), func() error {
+		return errorsFrom(git.RemoveWorktreeFromRepo(baseDir, worktreePath, branch, true, false))
+	})
 }
 
-func handlePostInitialization(sessionName string, noScripts, removeSession bool, currentSession string) error {
-	if !noScripts {
-		utils.ExecuteScriptInSession(sessionName, "go", "post.sh")
+// createOrRestoreSession brings back a session with its saved layout if it has one, e.g.
+// after the tmux server restarted, otherwise creates a plain one with env.
+func createOrRestoreSession(sessionName, sessionDir string, env ...string) error {
+	saved, exists, err := state.GetSession(sessionName)
+	if err == nil && exists && saved.Layout != nil && saved.WorktreePath == sessionDir {
+		return RestoreSession(saved)
+	}
+	tmux.CreateSessionInDirectory(sessionName, sessionDir, env...)
+	if !tmux.HasSession(sessionName) {
+		return fmt.Errorf("couldn't create session %s", sessionName)
 	}
+	return nil
+}
 
-	tmux.FinalizeSession(sessionName, currentSession, removeSession)
+func planPostInitialization(p *plan.Plan, baseDir, sessionName, worktreePath string, opts GoOptions) error {
+	if !opts.NoScripts {
+		hook := hookContext{
+			Command:  "go",
+			Phase:    "post",
+			BaseDir:  baseDir,
+			Worktree: worktreePath,
+			Branch:   opts.Branch,
+			Session:  sessionName,
+		}
+		if err := planScripts(p, hook); err != nil {
+			return err
+		}
+		if err := planResourceSetup(p, hook); err != nil {
+			return err
+		}
+	}
+
+	planSwitch(p, sessionName, opts)
 	return nil
 }
+
+// planSwitch adds switching to the session, and killing the one being left if asked to.
+// Outside tmux the client attaches instead, which has to be the last step as it replaces
+// twt; there's no current session to kill then.
+func planSwitch(p *plan.Plan, sessionName string, opts GoOptions) {
+	p.Add(plan.Tmux, tmux.SwitchDescription(sessionName), func() error {
+		return tmux.AttachOrSwitch(sessionName)
+	})
+	if opts.RemoveCurrentSession && tmux.InsideTmux() {
+		p.Add(plan.Tmux, fmt.Sprintf("kill the current session %s", opts.CurrentSession), func() error {
+			tmux.KillSession(opts.CurrentSession)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// dirExists reports whether path is a directory, e.g. a worktree that wasn't deleted.
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// errorsFrom turns the stderr lines of a failed command into an error.
+func errorsFrom(stderr []string) error {
+	if len(stderr) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(stderr, "; "))
+}
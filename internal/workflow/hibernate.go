@@ -0,0 +1,116 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/j-clemons/twt/internal/command"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+const hibernatePollInterval = 200 * time.Millisecond
+
+type HibernateOptions struct {
+	RestoreCommands []string
+	// How long pane processes get to exit after being interrupted
+	Grace time.Duration
+	// Where to go if the session being hibernated is the current one
+	Destinations   []string
+	CurrentSession string
+}
+
+// HibernateSessions hibernates each of sessions, carrying on past failures.
+func HibernateSessions(sessions []state.SessionInfo, opts HibernateOptions) []SessionResult {
+	return ExecuteBulk(PlanHibernate(sessions, opts), sessions)
+}
+
+// PlanHibernate lists the steps to hibernate each of sessions.
+func PlanHibernate(sessions []state.SessionInfo, opts HibernateOptions) *plan.Plan {
+	p := plan.New("hibernate")
+	for _, session := range sessions {
+		AddHibernateSteps(p, session, opts)
+	}
+	return p
+}
+
+// AddHibernateSteps adds the steps to hibernate a running twt session to p. Hibernating
+// frees the session's resources while keeping the worktree and state entry: the layout is
+// saved first so go or restore can bring it back, then pane processes are interrupted and
+// given the grace period to exit before the session is killed.
+func AddHibernateSteps(p *plan.Plan, session state.SessionInfo, opts HibernateOptions) {
+	target := session.Name
+
+	p.AddFor(target, plan.State, fmt.Sprintf("save the layout of %s", session.Name), func() error {
+		if err := SaveSession(session.Name, opts.RestoreCommands); err != nil {
+			return fmt.Errorf("couldn't save layout: %w", err)
+		}
+		return nil
+	})
+
+	p.AddFor(target, plan.Tmux, fmt.Sprintf("interrupt the processes in %s's panes, waiting up to %s", session.Name, opts.Grace), func() error {
+		panes, err := tmux.ListPaneProcesses(session.Name)
+		if err != nil {
+			return err
+		}
+		for _, pane := range panes {
+			tmux.SendKeys(pane.ID, "C-c")
+		}
+		waitForPanesIdle(panes, opts.Grace)
+		return nil
+	})
+
+	if session.Name == opts.CurrentSession {
+		addSwitchAwaySteps(p, session, RemoveOptions{Destinations: opts.Destinations})
+	}
+
+	p.AddFor(target, plan.Tmux, fmt.Sprintf("kill session %s", session.Name), func() error {
+		tmux.KillSession(session.Name)
+		return nil
+	})
+	p.AddFor(target, plan.State, fmt.Sprintf("mark %s as hibernated", session.Name), func() error {
+		return state.SetHibernated(session.Name, true)
+	})
+}
+
+// IdleSessions returns running, detached twt sessions not accessed for at least idleFor.
+func IdleSessions(idleFor time.Duration, exclude string) ([]state.SessionInfo, error) {
+	sessions, err := state.ListAllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var idle []state.SessionInfo
+	for _, session := range sessions {
+		if session.IsActive() && session.Attached == 0 && session.Name != exclude && session.TimeSinceAccessed() >= idleFor {
+			idle = append(idle, session)
+		}
+	}
+	return idle, nil
+}
+
+// waitForPanesIdle waits until no pane shell has child processes left, or the grace period
+// runs out.
+func waitForPanesIdle(panes []tmux.PaneProcess, grace time.Duration) {
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		busy := false
+		for _, pane := range panes {
+			if hasChildProcesses(pane.PID) {
+				busy = true
+				break
+			}
+		}
+		if !busy {
+			return
+		}
+		time.Sleep(hibernatePollInterval)
+	}
+}
+
+func hasChildProcesses(pid int) bool {
+	out, _ := command.Run("pgrep", "-P", strconv.Itoa(pid))
+	return len(out) > 0
+}
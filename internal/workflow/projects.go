@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/discovery"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+// AllProjects returns the repos found under the discovery roots, along with any registered
+// repos outside them. refresh rescans the roots instead of using the cached index.
+func AllProjects(cfg config.DiscoveryConfig, refresh bool) ([]discovery.Project, error) {
+	var projects []discovery.Project
+	if len(cfg.Roots) > 0 {
+		index, err := discovery.Load(cfg, refresh)
+		if err != nil {
+			return nil, err
+		}
+		projects = index.Projects
+	}
+
+	found := make(map[string]bool)
+	for _, project := range projects {
+		found[project.Path] = true
+	}
+	repos, err := state.ListRepos()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		if found[repo.Path] {
+			continue
+		}
+		// Registered repos are inspected every time, there are only a few of them
+		project, err := discovery.Inspect(repo.Path)
+		if err != nil {
+			continue
+		}
+		projects = append(projects, project)
+	}
+
+	if len(projects) == 0 {
+		return nil, errors.New("No projects found - add discovery.roots to the config, or register repos with 'twt repo add'")
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].Path < projects[j].Path
+	})
+	return projects, nil
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+// PrunableSessions returns those of sessions whose worktrees were all deleted without twt,
+// e.g. with rm -rf. Common sessions aren't worktrees, so they're never pruned.
+func PrunableSessions(sessions []state.SessionInfo) []state.SessionInfo {
+	var prunable []state.SessionInfo
+	for _, session := range sessions {
+		if session.IsCommon() {
+			continue
+		}
+		gone := true
+		for _, worktree := range sessionWorktrees(session) {
+			if dirExists(worktree.worktreePath) {
+				gone = false
+				break
+			}
+		}
+		if gone {
+			prunable = append(prunable, session)
+		}
+	}
+	return prunable
+}
+
+// PlanPrune lists the steps to clean up after each of sessions, whose worktrees are gone:
+// their resources are torn down, their compose projects brought down, they're killed if
+// running and unregistered, and git forgets the worktrees. A teardown may well fail without
+// its worktree, so that's only a warning.
+func PlanPrune(sessions []state.SessionInfo) (*plan.Plan, error) {
+	p := plan.New("prune")
+	for _, session := range sessions {
+		target := session.Name
+
+		if err := addTeardownSteps(p, target, session, true); err != nil {
+			return nil, err
+		}
+		if err := addComposeDownSteps(p, target, session, true); err != nil {
+			return nil, err
+		}
+
+		if tmux.HasSession(session.Name) {
+			p.AddFor(target, plan.Tmux, fmt.Sprintf("kill session %s", session.Name), func() error {
+				tmux.KillSession(session.Name)
+				return nil
+			})
+		}
+
+		p.AddFor(target, plan.State, fmt.Sprintf("unregister session %s", session.Name), func() error {
+			return state.UnregisterSession(session.Name)
+		})
+
+		for _, worktree := range sessionWorktrees(session) {
+			p.AddFor(target, plan.Git, fmt.Sprintf("prune worktree records in %s", worktree.repoPath), func() error {
+				return git.PruneWorktrees(worktree.repoPath)
+			})
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,26 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/state"
+)
+
+// ResolveRepo returns the base dir of the repo given by a registered alias or path, or by
+// the path of any dir inside a repo.
+func ResolveRepo(aliasOrPath string) (string, error) {
+	repo, ok, err := state.LookupRepo(aliasOrPath)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return repo.Path, nil
+	}
+
+	baseDir, err := git.BaseDirOf(aliasOrPath)
+	if err != nil {
+		return "", fmt.Errorf("%s isn't a registered repo alias or a path inside a repo", aliasOrPath)
+	}
+	return baseDir, nil
+}
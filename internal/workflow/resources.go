@@ -0,0 +1,177 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/provision"
+	"github.com/j-clemons/twt/internal/scripts"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/trust"
+)
+
+// resourceScript stands for a resource's commands where scripts are expected, so per_script
+// settings apply to it as provision/<name>, and approving it approves the manifest.
+func resourceScript(commonDir, name string) scripts.Script {
+	return scripts.Script{Name: "provision/" + name, Path: provision.Path(commonDir)}
+}
+
+// planResourceSetup adds a step setting up each resource in the repo's provision.json that
+// the worktree hasn't got yet, in order, once the user trusts the manifest. Each is recorded
+// on the session when set up, so rm can tear it down, and torn down if a later step fails.
+func planResourceSetup(p *plan.Plan, hook hookContext) error {
+	commonDir, err := CommonDir(hook.BaseDir)
+	if err != nil {
+		// Resources are optional
+		return nil
+	}
+	manifest, err := provision.Load(commonDir)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Resources) == 0 {
+		return nil
+	}
+
+	setUp := make(map[string]bool)
+	if session, ok, err := state.GetSession(hook.Session); err == nil && ok {
+		for _, resource := range session.Resources {
+			if resource.RepoPath == hook.BaseDir {
+				setUp[resource.Name] = true
+			}
+		}
+	}
+
+	manifestPath := provision.Path(commonDir)
+	if !trust.IsTrusted(manifestPath) {
+		p.Note("%s isn't trusted yet, you'll be asked before its resources are set up", manifestPath)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	for _, resource := range manifest.Resources {
+		if setUp[resource.Name] {
+			p.Note("%s is already set up for %s", resource.Name, hook.Session)
+			continue
+		}
+
+		script := resourceScript(commonDir, resource.Name)
+		settings, err := scriptSettings(cfg.Scripts, script)
+		if err != nil {
+			return err
+		}
+		opts, err := scriptRunOptions(settings, script, hook, commonDir)
+		if err != nil {
+			return err
+		}
+		info := state.ResourceInfo{
+			Name:     resource.Name,
+			RepoPath: hook.BaseDir,
+			Dir:      opts.Dir,
+		}
+
+		name := resource.Name
+		teardown := func() error {
+			return scripts.RunCommand(script.Name, info.Teardown, opts)
+		}
+		description := fmt.Sprintf("set up %s in %s: %s", name, opts.Dir, resource.Setup)
+		var step *plan.Step
+		step = p.Add(plan.Script, hookDescription(description, settings), hookStep(p, settings, func() error {
+			// The manifest may have changed since planning, so what runs is what was approved
+			content, err := trust.Approve(provision.FileName, manifestPath)
+			if err != nil {
+				return err
+			}
+			approved, err := provision.Parse(content)
+			if err != nil {
+				return err
+			}
+			resource, ok := approved.Find(name)
+			if !ok {
+				return fmt.Errorf("%s no longer declares %s", manifestPath, name)
+			}
+			info.Teardown = resource.Teardown
+			info.ComposeDown = resource.ComposeDown
+			if info.Teardown != "" {
+				step.OnRollback(fmt.Sprintf("tear down %s: %s", name, info.Teardown), teardown)
+			} else {
+				step.OnRollback("", nil)
+			}
+			if err := scripts.RunCommand(script.Name, resource.Setup, opts); err != nil {
+				return err
+			}
+			info.SetupAt = time.Now()
+			return state.RecordResource(hook.Session, info)
+		}))
+		if resource.Teardown != "" {
+			step.OnRollback(fmt.Sprintf("tear down %s: %s", name, resource.Teardown), teardown)
+		}
+	}
+	return nil
+}
+
+// addTeardownSteps adds steps tearing down the session's resources in the reverse order they
+// were set up, forgetting each once it's gone. A resource whose worktree is gone is torn down
+// from its repo's base dir. With force, a failing teardown is a warning rather than stopping
+// the removal.
+func addTeardownSteps(p *plan.Plan, target string, session state.SessionInfo, force bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	for i := len(session.Resources) - 1; i >= 0; i-- {
+		resource := session.Resources[i]
+		forget := func() error {
+			return state.ForgetResource(session.Name, resource.Name, resource.RepoPath)
+		}
+		if resource.Teardown == "" {
+			p.AddFor(target, plan.State, fmt.Sprintf("forget %s, it has no teardown", resource.Name), forget)
+			continue
+		}
+
+		dir := resource.Dir
+		if !dirExists(dir) {
+			dir = resource.RepoPath
+		}
+		commonDir, _ := CommonDir(resource.RepoPath)
+		script := resourceScript(commonDir, resource.Name)
+		settings, err := scriptSettings(cfg.Scripts, script)
+		if err != nil {
+			// A broken setting mustn't keep a resource from being torn down
+			settings = defaultScriptSettings(script)
+		}
+		if force && settings.OnFailure == config.FailureAbort {
+			settings.OnFailure = config.FailureWarn
+		}
+		hook := hookContext{
+			Command:   "rm",
+			Phase:     "teardown",
+			BaseDir:   resource.RepoPath,
+			Worktree:  dir,
+			Branch:    session.Branch,
+			Session:   session.Name,
+			Workspace: session.Workspace,
+		}
+		opts, err := scriptRunOptions(settings, script, hook, commonDir)
+		if err != nil {
+			if opts, err = scriptRunOptions(defaultScriptSettings(script), script, hook, commonDir); err != nil {
+				return err
+			}
+		}
+		// Torn down where it was set up
+		opts.Dir = dir
+
+		description := fmt.Sprintf("tear down %s in %s: %s", resource.Name, dir, resource.Teardown)
+		p.AddFor(target, plan.Script, hookDescription(description, settings), hookStep(p, settings, func() error {
+			if err := scripts.RunCommand(script.Name, resource.Teardown, opts); err != nil {
+				return err
+			}
+			return forget()
+		}))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+)
+
+type SessionResult struct {
+	Session state.SessionInfo
+	Err     error
+}
+
+// SaveSession records the windows, panes and whitelisted pane commands of a running twt
+// session, so it can be recreated after the tmux server stops.
+func SaveSession(sessionName string, restoreCommands []string) error {
+	layout, err := tmux.CaptureLayout(sessionName, restoreCommands)
+	if err != nil {
+		return err
+	}
+	return state.SaveLayout(sessionName, layout)
+}
+
+// PlanSaveAll lists a save step for every running twt session, and returns those sessions.
+func PlanSaveAll(restoreCommands []string) (*plan.Plan, []state.SessionInfo, error) {
+	sessions, err := state.ListAllSessions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := plan.New("save --all")
+	var running []state.SessionInfo
+	for _, session := range sessions {
+		if !session.IsActive() {
+			continue
+		}
+		running = append(running, session)
+		p.AddFor(session.Name, plan.State, fmt.Sprintf("save the layout of %s", session.Name), func() error {
+			return SaveSession(session.Name, restoreCommands)
+		})
+	}
+	return p, running, nil
+}
+
+// RestoreSession recreates an inactive or hibernated twt session in its worktree, with its
+// saved layout if it has one. Running sessions are left alone.
+func RestoreSession(session state.SessionInfo) error {
+	if tmux.HasSession(session.Name) {
+		return nil
+	}
+	p, err := PlanRestoreSession(session)
+	if err != nil {
+		return err
+	}
+	return p.Execute()
+}
+
+// PlanRestoreSession lists the steps to restore a session, see RestoreSession.
+func PlanRestoreSession(session state.SessionInfo) (*plan.Plan, error) {
+	p := plan.New(fmt.Sprintf("restore %s", session.Name))
+	if err := AddRestoreSteps(p, session); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AddRestoreSteps adds the steps to restore a session to p, see RestoreSession.
+func AddRestoreSteps(p *plan.Plan, session state.SessionInfo) error {
+	description := fmt.Sprintf("create session %s in %s", session.Name, session.WorktreePath)
+	if session.Layout != nil {
+		description = fmt.Sprintf("recreate session %s with its saved layout (%d windows)", session.Name, len(session.Layout.Windows))
+	}
+	if !dirExists(session.WorktreePath) {
+		p.Note("worktree %s of %s no longer exists", session.WorktreePath, session.Name)
+	}
+
+	env, err := sessionEnv(session)
+	if err != nil {
+		return err
+	}
+	p.AddFor(session.Name, plan.Tmux, withEnv(description, env), func() error {
+		if !dirExists(session.WorktreePath) {
+			return fmt.Errorf("worktree %s no longer exists", session.WorktreePath)
+		}
+		if session.Layout != nil {
+			return tmux.RestoreLayout(session.Name, session.Layout, session.WorktreePath, env...)
+		}
+		if session.Workspace != "" {
+			return openWorkspaceSession(session, workspaceRepos(session))
+		}
+		return tmux.CreateSessionInDirectory(session.Name, session.WorktreePath, env...)
+	})
+	p.AddFor(session.Name, plan.Tmux, fmt.Sprintf("mark %s as managed by twt", session.Name), func() error {
+		state.SetSessionEnvironment(session)
+		return nil
+	})
+
+	if session.Hibernated {
+		p.AddFor(session.Name, plan.State, fmt.Sprintf("clear the hibernated flag of %s", session.Name), func() error {
+			return state.SetHibernated(session.Name, false)
+		})
+	}
+	return nil
+}
+
+// PlanRestoreSessions lists the steps to restore every inactive session out of sessions,
+// and returns those sessions.
+func PlanRestoreSessions(sessions []state.SessionInfo) (*plan.Plan, []state.SessionInfo, error) {
+	p := plan.New("restore --all")
+	var inactive []state.SessionInfo
+	for _, session := range sessions {
+		if session.IsActive() {
+			continue
+		}
+		inactive = append(inactive, session)
+		if err := AddRestoreSteps(p, session); err != nil {
+			return nil, nil, err
+		}
+	}
+	return p, inactive, nil
+}
+
+// ExecuteBulk runs a plan of steps targeted at each of sessions, and pairs every session with
+// its outcome. A failure only stops the rest of that session's steps.
+func ExecuteBulk(p *plan.Plan, sessions []state.SessionInfo) []SessionResult {
+	errs := p.ExecuteEach()
+
+	var results []SessionResult
+	for _, session := range sessions {
+		results = append(results, SessionResult{Session: session, Err: errs[session.Name]})
+	}
+	return results
+}
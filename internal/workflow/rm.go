@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+type RemoveMode int
+
+const (
+	KillSessionOnly RemoveMode = iota
+	RemoveSessionWorktree
+	RemoveSessionWorktreeAndBranch
+)
+
+func (m RemoveMode) String() string {
+	switch m {
+	case RemoveSessionWorktree:
+		return "remove worktree"
+	case RemoveSessionWorktreeAndBranch:
+		return "remove worktree and branch"
+	default:
+		return "kill session"
+	}
+}
+
+type RemoveOptions struct {
+	// Remove even with uncommitted changes or an unmerged branch
+	Force bool
+	// Archive the worktree's uncommitted work first, see ArchiveSession
+	Archive bool
+	// Session the client is on. If it's the one being removed the client is switched to
+	// Target, or the first of Destinations available, before it's killed.
+	CurrentSession string
+	Target         string
+	Destinations   []string
+}
+
+// RemoveSession tears down a registered session. Worktree and branch removal act on the
+// session's own repo, so sessions from several repos can be removed from anywhere.
+func RemoveSession(session state.SessionInfo, mode RemoveMode, opts RemoveOptions) error {
+	p, err := PlanRemoveSession(session, mode, opts)
+	if err != nil {
+		return err
+	}
+	return p.Execute()
+}
+
+// PlanRemoveSession lists the steps to remove a session, see RemoveSession.
+func PlanRemoveSession(session state.SessionInfo, mode RemoveMode, opts RemoveOptions) (*plan.Plan, error) {
+	p := plan.New(fmt.Sprintf("%s %s", mode, session.Name))
+	if err := AddRemoveSteps(p, session, mode, opts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AddRemoveSteps adds the steps to remove a session to p, targeted at the session so bulk
+// removals can be run with ExecuteEach.
+func AddRemoveSteps(p *plan.Plan, session state.SessionInfo, mode RemoveMode, opts RemoveOptions) error {
+	target := session.Name
+	// A common session's dir isn't a worktree, removing it only stops and unregisters it
+	removeWorktree := mode != KillSessionOnly && !session.IsCommon()
+
+	worktrees := sessionWorktrees(session)
+
+	if removeWorktree && opts.Archive {
+		for _, worktree := range worktrees {
+			archived := session
+			archived.RepoPath, archived.WorktreePath = worktree.repoPath, worktree.worktreePath
+			if session.Workspace != "" {
+				// Named as the repo's own session, so it's restored from within the repo
+				archived.Name = utils.GenerateSessionNameForRepo(worktree.repoPath, session.Branch)
+			}
+			p.AddFor(target, plan.Filesystem, fmt.Sprintf("archive uncommitted work in %s", worktree.worktreePath), func() error {
+				if _, err := ArchiveSession(archived); err != nil {
+					return fmt.Errorf("couldn't archive worktree, not removing it: %w", err)
+				}
+				return nil
+			})
+		}
+	}
+
+	if removeWorktree {
+		if err := addTeardownSteps(p, target, session, opts.Force); err != nil {
+			return err
+		}
+		if err := addComposeDownSteps(p, target, session, opts.Force); err != nil {
+			return err
+		}
+
+		for _, worktree := range worktrees {
+			description := fmt.Sprintf("remove worktree %s", worktree.worktreePath)
+			if opts.Force {
+				description += " (forced)"
+			}
+			p.AddFor(target, plan.Git, description, func() error {
+				errs := git.RemoveWorktreeFromRepo(worktree.repoPath, worktree.worktreePath, session.Branch, opts.Force, false)
+				if len(errs) > 0 {
+					return fmt.Errorf("error removing worktree: %v", errs)
+				}
+				return nil
+			})
+		}
+	}
+
+	if session.Name == opts.CurrentSession {
+		addSwitchAwaySteps(p, session, opts)
+	}
+
+	if tmux.HasSession(session.Name) {
+		p.AddFor(target, plan.Tmux, fmt.Sprintf("kill session %s", session.Name), func() error {
+			tmux.KillSession(session.Name)
+			return nil
+		})
+	}
+
+	if mode == KillSessionOnly {
+		return nil
+	}
+
+	p.AddFor(target, plan.State, fmt.Sprintf("unregister session %s", session.Name), func() error {
+		if err := state.UnregisterSession(session.Name); err != nil {
+			return fmt.Errorf("worktree removed but failed to unregister session: %w", err)
+		}
+		return nil
+	})
+
+	if mode == RemoveSessionWorktreeAndBranch && !session.IsCommon() {
+		for _, worktree := range worktrees {
+			description := fmt.Sprintf("delete branch %s", session.Branch)
+			if session.Workspace != "" {
+				description += fmt.Sprintf(" in %s", worktree.repoPath)
+			}
+			if opts.Force {
+				description += " (forced)"
+			}
+			p.AddFor(target, plan.Git, description, func() error {
+				if errs := git.DeleteBranchFromRepo(worktree.repoPath, session.Branch, opts.Force); len(errs) > 0 {
+					return fmt.Errorf("worktree removed but branch wasn't deleted: %v", errs)
+				}
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+func addSwitchAwaySteps(p *plan.Plan, session state.SessionInfo, opts RemoveOptions) {
+	if opts.Target != "" {
+		p.AddFor(session.Name, plan.Tmux, fmt.Sprintf("switch to %s", opts.Target), func() error {
+			tmux.SwitchToSession(opts.Target)
+			return nil
+		})
+		return
+	}
+
+	description := fmt.Sprintf("switch to the first available of: %s", strings.Join(opts.Destinations, ", "))
+	p.AddFor(session.Name, plan.Tmux, description, func() error {
+		destination, err := ChooseDestination(opts.Destinations, session.RepoPath, session.Name)
+		if err != nil {
+			return fmt.Errorf("no session to switch to: %w", err)
+		}
+		tmux.SwitchToSession(destination)
+		return nil
+	})
+}
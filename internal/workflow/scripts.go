@@ -0,0 +1,206 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/j-clemons/twt/internal/config"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/scripts"
+	"github.com/j-clemons/twt/internal/trust"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+// hookContext is what a command's scripts run for, passed to them as TWT_* vars.
+type hookContext struct {
+	Command string
+	Phase   string
+	// The repo's base dir
+	BaseDir   string
+	Worktree  string
+	Branch    string
+	Session   string
+	Workspace string
+}
+
+// planScripts adds a step running each script for the context's phase in the repo, in
+// order. Scripts disabled in the config are noted instead. A script that's new or changed
+// since the user trusted it only runs once they approve it. A script that fails, or isn't
+// approved, aborts the plan unless its on_failure setting lets it carry on.
+func planScripts(p *plan.Plan, hook hookContext) error {
+	commonDir, err := CommonDir(hook.BaseDir)
+	if err != nil {
+		// Scripts are optional
+		return nil
+	}
+	found, err := scripts.Discover(filepath.Join(commonDir, "scripts"), hook.Command, hook.Phase)
+	if err != nil {
+		return fmt.Errorf("couldn't read %s scripts: %w", hook.Command, err)
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	for _, script := range found {
+		if !script.Enabled(cfg.Scripts.Enabled) {
+			p.Note("skip %s, disabled in the config", script.Name)
+			continue
+		}
+		settings, err := scriptSettings(cfg.Scripts, script)
+		if err != nil {
+			return err
+		}
+		opts, err := scriptRunOptions(settings, script, hook, commonDir)
+		if err != nil {
+			return err
+		}
+
+		if !trust.IsTrusted(script.Path) {
+			p.Note("%s isn't trusted yet, you'll be asked before it runs", script.Name)
+		}
+		p.Add(plan.Script, hookDescription(fmt.Sprintf("run %s in %s", script.Name, opts.Dir), settings), hookStep(p, settings, func() error {
+			content, err := trust.Approve(script.Name, script.Path)
+			if err != nil {
+				return err
+			}
+			return script.Run(content, opts)
+		}))
+	}
+	return nil
+}
+
+// hookDescription notes a hook's failure policy in its step's description, unless it's the
+// default of aborting.
+func hookDescription(description string, settings config.ScriptSettings) string {
+	if settings.OnFailure != config.FailureAbort {
+		description += fmt.Sprintf(" (on failure: %s)", settings.OnFailure)
+	}
+	return description
+}
+
+// hookStep runs a script or command for a step, applying its failure policy: a failure
+// aborts the plan, or is reported as a warning, or ignored.
+func hookStep(p *plan.Plan, settings config.ScriptSettings, run func() error) func() error {
+	return func() error {
+		err := run()
+		if err == nil {
+			return nil
+		}
+		switch settings.OnFailure {
+		case config.FailureWarn:
+			// Shown now too, as attaching outside tmux replaces twt before it can report
+			fmt.Fprintf(os.Stderr, "Warning: %s, carrying on\n", err)
+			p.Warn("%s", err)
+			return nil
+		case config.FailureIgnore:
+			return nil
+		}
+		return err
+	}
+}
+
+// defaultScriptSettings returns how the script runs when its settings are broken.
+func defaultScriptSettings(script scripts.Script) config.ScriptSettings {
+	settings := config.Default().Scripts.ScriptSettings
+	settings.OnFailure = config.DefaultOnFailure(script.Phase)
+	return settings
+}
+
+// scriptSettings returns how the script runs: the settings of the most specific per_script
+// pattern matching it, falling back to the defaults.
+func scriptSettings(cfg config.ScriptsConfig, script scripts.Script) (config.ScriptSettings, error) {
+	settings := cfg.ScriptSettings
+	patterns := make([]string, 0, len(cfg.PerScript))
+	for pattern := range cfg.PerScript {
+		patterns = append(patterns, pattern)
+	}
+	if pattern, ok := script.Match(patterns); ok {
+		override := cfg.PerScript[pattern]
+		if override.Timeout != "" {
+			settings.Timeout = override.Timeout
+		}
+		if override.Env != "" {
+			settings.Env = override.Env
+		}
+		if override.Dir != "" {
+			settings.Dir = override.Dir
+		}
+		if override.OnFailure != "" {
+			settings.OnFailure = override.OnFailure
+		}
+	}
+
+	defaults := config.Default().Scripts.ScriptSettings
+	for _, setting := range []struct {
+		name     string
+		value    *string
+		fallback string
+		allowed  []string
+	}{
+		{"env", &settings.Env, defaults.Env, config.ScriptEnvs},
+		{"dir", &settings.Dir, defaults.Dir, config.ScriptDirs},
+		{"on_failure", &settings.OnFailure, config.DefaultOnFailure(script.Phase), config.FailurePolicies},
+	} {
+		if *setting.value == "" {
+			*setting.value = setting.fallback
+		}
+		if !slices.Contains(setting.allowed, *setting.value) {
+			return settings, fmt.Errorf("%s: unknown scripts %s %q, use one of %v", script.Name, setting.name, *setting.value, setting.allowed)
+		}
+	}
+	return settings, nil
+}
+
+func scriptRunOptions(settings config.ScriptSettings, script scripts.Script, hook hookContext, commonDir string) (scripts.RunOptions, error) {
+	var timeout time.Duration
+	if settings.Timeout != "" && settings.Timeout != "0" {
+		var err error
+		timeout, err = utils.ParseDuration(settings.Timeout)
+		if err != nil {
+			return scripts.RunOptions{}, fmt.Errorf("%s: scripts timeout: %w", script.Name, err)
+		}
+	}
+
+	dir := hook.Worktree
+	switch settings.Dir {
+	case config.DirRepo:
+		dir = hook.BaseDir
+	case config.DirCommon:
+		dir = commonDir
+	}
+
+	env := []string{
+		"TWT_COMMAND=" + hook.Command,
+		"TWT_PHASE=" + hook.Phase,
+		"TWT_SCRIPT=" + script.Name,
+		"TWT_SCRIPT_PATH=" + script.Path,
+		"TWT_SCRIPT_DIR=" + filepath.Dir(script.Path),
+		"TWT_REPO=" + hook.BaseDir,
+		"TWT_COMMON_DIR=" + commonDir,
+		"TWT_WORKTREE=" + hook.Worktree,
+		"TWT_BRANCH=" + hook.Branch,
+		"TWT_SESSION=" + hook.Session,
+	}
+	if hook.Workspace != "" {
+		env = append(env, "TWT_WORKSPACE="+hook.Workspace)
+	}
+	project, err := composeEnv(hook.BaseDir, hook.Branch)
+	if err != nil {
+		return scripts.RunOptions{}, err
+	}
+	env = append(env, project...)
+
+	return scripts.RunOptions{
+		Dir:      dir,
+		Timeout:  timeout,
+		CleanEnv: settings.Env == config.EnvClean,
+		Env:      env,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package workflow_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j-clemons/twt/internal/workflow"
+)
+
+func TestPostScriptsWarnByDefault(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{name: "Default", expected: "run go/post.sh in %s (on failure: warn)"},
+		{name: "Set to abort", config: `{"scripts":{"on_failure":"abort"}}`, expected: "run go/post.sh in %s\n"},
+		{name: "Set per script", config: `{"scripts":{"per_script":{"go/*":{"on_failure":"ignore"}}}}`, expected: "run go/post.sh in %s (on failure: ignore)"},
+	}
+
+	for _, c := range cases {
+		dir := isolate(t, c.config)
+		baseDir := filepath.Join(dir, "api.git")
+		git(t, dir, "init", "--quiet", "--bare", baseDir)
+		baseDir, err := filepath.EvalSymlinks(baseDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script := filepath.Join(baseDir, "common", "scripts", "go", "post.sh")
+		if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Chdir(baseDir)
+
+		p, err := workflow.PlanGo(workflow.GoOptions{Branch: "feature"})
+		if err != nil {
+			t.Fatalf("%s: Expected success but got error: %s", c.name, err)
+		}
+		expected := strings.ReplaceAll(c.expected, "%s", filepath.Join(baseDir, "feature"))
+		if out := printed(t, p); !strings.Contains(out, expected) {
+			t.Fatalf("%s: Expected %q in the plan but got:\n%s", c.name, expected, out)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/j-clemons/twt/internal/git"
+	"github.com/j-clemons/twt/internal/plan"
+	"github.com/j-clemons/twt/internal/state"
+	"github.com/j-clemons/twt/internal/tmux"
+	"github.com/j-clemons/twt/internal/utils"
+)
+
+// WorkspaceSessionName names the session of a workspace for a branch, see
+// utils.GenerateWorkspaceSessionName.
+func WorkspaceSessionName(workspace, branch string) string {
+	return utils.GenerateWorkspaceSessionName(workspace, branch)
+}
+
+// LoadWorkspace returns the workspace with the given name, or an error if there isn't one.
+func LoadWorkspace(name string) (state.Workspace, error) {
+	workspace, ok, err := state.GetWorkspace(name)
+	if err != nil {
+		return state.Workspace{}, err
+	}
+	if !ok {
+		return state.Workspace{}, fmt.Errorf("no workspace %s - create it with 'twt workspace add'", name)
+	}
+	if len(workspace.Repos) == 0 {
+		return state.Workspace{}, fmt.Errorf("workspace %s has no repos", name)
+	}
+	return workspace, nil
+}
+
+// worktreeOf returns where the branch is checked out in the repo, or where go would put it
+// if it isn't.
+func worktreeOf(repoPath, branch string) (string, bool, error) {
+	worktrees, err := git.ListWorktrees(repoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", repoPath, err)
+	}
+	for _, worktree := range worktrees {
+		if worktree.Branch == branch && !worktree.Bare {
+			return worktree.Path, true, nil
+		}
+	}
+	return filepath.Join(repoPath, utils.GenerateWorktreeNameFromBranch(branch)), false, nil
+}
+
+// PlanGoWorkspace works out what go needs to do for the branch across every repo of the
+// workspace: create any missing worktrees (and branches), then open one session with a
+// window per repo, or switch to it if it's running.
+func PlanGoWorkspace(workspace state.Workspace, opts GoOptions) (*plan.Plan, error) {
+	sessionName := WorkspaceSessionName(workspace.Name, opts.Branch)
+	p := plan.New(fmt.Sprintf("go --workspace %s %s", workspace.Name, opts.Branch))
+
+	if tmux.HasSession(sessionName) {
+		p.Add(plan.State, fmt.Sprintf("record access to %s", sessionName), func() error {
+			state.UpdateLastAccessed(sessionName)
+			return nil
+		})
+		planSwitch(p, sessionName, opts)
+		return p, nil
+	}
+
+	worktrees := make(map[string]string)
+	for _, repoPath := range workspace.Repos {
+		worktreePath, exists, err := worktreeOf(repoPath, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+		worktrees[repoPath] = worktreePath
+		if !exists {
+			startPoint := ""
+			if !git.HasBranchInRepo(repoPath, opts.Branch) {
+				startPoint = "HEAD"
+			}
+			addWorktreeSteps(p, repoPath, worktreePath, opts.Branch, startPoint)
+		}
+	}
+
+	saved, registered, err := state.GetSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	session := state.SessionInfo{
+		Name:         sessionName,
+		RepoPath:     workspace.Repos[0],
+		Branch:       opts.Branch,
+		WorktreePath: worktrees[workspace.Repos[0]],
+		Workspace:    workspace.Name,
+		Worktrees:    worktrees,
+	}
+
+	description := fmt.Sprintf("create session %s with a window for each of %d repos", sessionName, len(workspace.Repos))
+	if registered && saved.Layout != nil {
+		description = fmt.Sprintf("restore session %s with its saved layout (%d windows)", sessionName, len(saved.Layout.Windows))
+		session.Layout = saved.Layout
+	}
+	p.Add(plan.Tmux, description, func() error {
+		if session.Layout != nil {
+			return tmux.RestoreLayout(sessionName, session.Layout, session.WorktreePath)
+		}
+		return openWorkspaceSession(session, workspace.Repos)
+	}).OnRollback(fmt.Sprintf("kill session %s", sessionName), func() error {
+		tmux.KillSession(sessionName)
+		return nil
+	})
+
+	step := p.Add(plan.State, fmt.Sprintf("register session %s", sessionName), func() error {
+		return state.RegisterWorkspaceSession(sessionName, workspace, opts.Branch, worktrees)
+	})
+	if !registered {
+		step.OnRollback(fmt.Sprintf("unregister session %s", sessionName), func() error {
+			return state.UnregisterSession(sessionName)
+		})
+	}
+
+	if !opts.NoScripts {
+		for _, repoPath := range workspace.Repos {
+			hook := hookContext{
+				Command:   "go",
+				Phase:     "post",
+				BaseDir:   repoPath,
+				Worktree:  worktrees[repoPath],
+				Branch:    opts.Branch,
+				Session:   sessionName,
+				Workspace: workspace.Name,
+			}
+			if err := planScripts(p, hook); err != nil {
+				return nil, err
+			}
+			if err := planResourceSetup(p, hook); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	planSwitch(p, sessionName, opts)
+	return p, nil
+}
+
+// openWorkspaceSession creates a session with a window per repo, named by the repo's alias
+// and started in its worktree, in the order of repos.
+func openWorkspaceSession(session state.SessionInfo, repos []string) error {
+	aliases := make(map[string]string)
+	if registered, err := state.ListRepos(); err == nil {
+		for _, repo := range registered {
+			aliases[repo.Path] = repo.Alias
+		}
+	}
+
+	for i, repoPath := range repos {
+		name := aliases[repoPath]
+		if name == "" {
+			name = state.DefaultRepoAlias(repoPath)
+		}
+
+		var err error
+		if i == 0 {
+			err = tmux.CreateSessionWithWindow(session.Name, name, session.Worktrees[repoPath])
+		} else {
+			err = tmux.NewWindow(session.Name, name, session.Worktrees[repoPath])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceRepos returns the repos of a workspace session in window order: the workspace's
+// own order if it's still defined, otherwise its first repo then the rest by path.
+func workspaceRepos(session state.SessionInfo) []string {
+	if workspace, ok, err := state.GetWorkspace(session.Workspace); err == nil && ok {
+		var repos []string
+		for _, repoPath := range workspace.Repos {
+			if _, ok := session.Worktrees[repoPath]; ok {
+				repos = append(repos, repoPath)
+			}
+		}
+		if len(repos) == len(session.Worktrees) {
+			return repos
+		}
+	}
+
+	repos := []string{session.RepoPath}
+	var rest []string
+	for repoPath := range session.Worktrees {
+		if repoPath != session.RepoPath {
+			rest = append(rest, repoPath)
+		}
+	}
+	sort.Strings(rest)
+	return append(repos, rest...)
+}
+
+// WorkspaceSession returns the registered session of the workspace for branch, or one
+// describing the branch's worktrees in the workspace's repos if there isn't one, so a
+// workspace can be removed however it was created.
+func WorkspaceSession(workspace state.Workspace, branch string) (state.SessionInfo, error) {
+	sessionName := WorkspaceSessionName(workspace.Name, branch)
+	session, registered, err := state.GetSession(sessionName)
+	if err != nil {
+		return state.SessionInfo{}, err
+	}
+	if registered && len(session.Worktrees) > 0 {
+		return session, nil
+	}
+
+	session = state.SessionInfo{
+		Name:      sessionName,
+		RepoName:  workspace.Name,
+		Branch:    branch,
+		Workspace: workspace.Name,
+		Worktrees: make(map[string]string),
+	}
+	for _, repoPath := range workspace.Repos {
+		worktreePath, exists, err := worktreeOf(repoPath, branch)
+		if err != nil {
+			return state.SessionInfo{}, err
+		}
+		if !exists {
+			continue
+		}
+		if session.RepoPath == "" {
+			session.RepoPath = repoPath
+			session.WorktreePath = worktreePath
+		}
+		session.Worktrees[repoPath] = worktreePath
+	}
+	if len(session.Worktrees) == 0 {
+		return state.SessionInfo{}, fmt.Errorf("branch %s isn't checked out in any repo of workspace %s", branch, workspace.Name)
+	}
+	return session, nil
+}
+
+// repoWorktree is a worktree a session works in, and the repo it's a worktree of.
+type repoWorktree struct {
+	repoPath     string
+	worktreePath string
+}
+
+// sessionWorktrees returns the worktree of a session, or each of a workspace session's.
+func sessionWorktrees(session state.SessionInfo) []repoWorktree {
+	if session.Workspace == "" || len(session.Worktrees) == 0 {
+		return []repoWorktree{{session.RepoPath, session.WorktreePath}}
+	}
+	var worktrees []repoWorktree
+	for _, repoPath := range workspaceRepos(session) {
+		worktrees = append(worktrees, repoWorktree{repoPath, session.Worktrees[repoPath]})
+	}
+	return worktrees
+}